@@ -2,96 +2,351 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/ybotet/notes-api-optimization/internal/advisor"
+	"github.com/ybotet/notes-api-optimization/internal/cache"
+	"github.com/ybotet/notes-api-optimization/internal/config"
 	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/gqlapi"
+	"github.com/ybotet/notes-api-optimization/internal/grpcapi"
 	"github.com/ybotet/notes-api-optimization/internal/handlers"
+	"github.com/ybotet/notes-api-optimization/internal/logging"
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+	"github.com/ybotet/notes-api-optimization/internal/middleware"
+	"github.com/ybotet/notes-api-optimization/internal/migrations"
+	"github.com/ybotet/notes-api-optimization/internal/pb"
+	"github.com/ybotet/notes-api-optimization/internal/tracing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
+	// Configuración: archivo YAML (-config), variables de entorno y flags
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if cfg.PrintOnly {
+		if err := cfg.Print(os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 0. Logging estructurado
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		slog.Error("configuración de logging inválida", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	slog.Info("configuración efectiva", "config", cfg.Effective())
+
+	// Trazas OpenTelemetry. Sin tracing.otlp_endpoint el exportador OTLP usa
+	// OTEL_EXPORTER_OTLP_ENDPOINT y OTEL_EXPORTER_OTLP_INSECURE.
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName:  "notes-api",
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		FilePath:     cfg.Tracing.File,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal("error configurando trazas", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("error cerrando trazas", "error", err)
+		}
+	}()
+
+	// Migraciones al arrancar (database.auto_migrate). El advisory lock de las
+	// migraciones permite que varias instancias arranquen a la vez.
+	if cfg.Database.AutoMigrate {
+		if err := autoMigrate(cfg.Database.MigrationsURL); err != nil {
+			fatal("error aplicando migraciones", err)
+		}
+	}
+
 	// 1. Inicializar base de datos
-	if err := db.InitDB(); err != nil {
-		log.Fatal("Error inicializando base de datos:", err)
+	database, err := db.Open(context.Background(), "primary", cfg.Database, logger)
+	if err != nil {
+		fatal("error inicializando base de datos", err)
 	}
-	defer db.CloseDB()
+	defer database.Close()
+	pool := database.Pool()
 
 	// 2. Crear repositorio
-	repo := db.NewPostgresRepository(db.GetPool())
+	repo := db.NewPostgresRepository(pool)
+
+	// Métricas de Prometheus del pool (leídas en cada scrape de /metrics)
+	metrics.Registry.MustRegister(metrics.NewPoolCollector(database.Name(), pool))
+
+	// Réplicas de lectura opcionales (database.replica_urls)
+	if len(cfg.Database.ReplicaURLs) > 0 {
+		replicas := make([]*db.Database, 0, len(cfg.Database.ReplicaURLs))
+		for i, url := range cfg.Database.ReplicaURLs {
+			replicaCfg := cfg.Database
+			replicaCfg.URL = url
+			// Las réplicas son de solo lectura: no pueden guardar planes en slow_query_plans
+			replicaCfg.SlowQueryExplain = false
+
+			replica, err := db.Open(context.Background(), fmt.Sprintf("replica-%d", i+1), replicaCfg, logger)
+			if err != nil {
+				fatal("error inicializando réplica", err)
+			}
+			defer replica.Close()
+
+			metrics.Registry.MustRegister(metrics.NewPoolCollector(replica.Name(), replica.Pool()))
+			replicas = append(replicas, replica)
+		}
+
+		router := db.NewReplicaRouter(pool, replicas, db.ReplicaOptions{
+			CheckInterval:  cfg.Database.ReplicaCheckInterval,
+			MaxLag:         cfg.Database.ReplicaMaxLag,
+			ReadYourWrites: cfg.Database.ReadYourWrites,
+		}, logger)
+		routerCtx, stopRouter := context.WithCancel(context.Background())
+		defer stopRouter()
+		router.Start(routerCtx)
+
+		repo = repo.WithReplicas(router)
+	}
+
+	// Lecturas idénticas simultáneas comparten una consulta (cache.coalesce)
+	var notesRepo db.Repository = repo
+	if cfg.Cache.Coalesce {
+		notesRepo = db.NewCoalescingRepository(notesRepo)
+	}
+
+	// Caches locales que se invalidan con los cambios hechos por otras instancias
+	var noteChangeHandlers []db.NoteChangeHandler
+
+	// Cache opcional de GetNoteByID y GetNotesBatch (cache.enabled)
+	if cfg.Cache.Enabled {
+		switch cfg.Cache.Backend {
+		case "redis":
+			client, err := cache.NewRESPClient(context.Background(), cfg.Cache.RedisURL, cache.DefaultRESPOptions)
+			if err != nil {
+				fatal("error conectando al cache", err)
+			}
+			defer client.Close()
+			// Las escrituras invalidan el cache compartido: no hace falta el listener
+			notesRepo = db.NewCachedRepository(notesRepo, db.NewRemoteNoteCache(client, cfg.Cache.TTL))
+
+		default:
+			cached := db.NewCachedRepository(notesRepo, db.NewLocalNoteCache(cfg.Cache.Size, cfg.Cache.TTL))
+			notesRepo = cached
+			noteChangeHandlers = append(noteChangeHandlers, cached)
+		}
+	}
 
 	// 3. Crear handlers
-	noteHandler := handlers.NewNoteHandler(repo)
-	healthHandler := handlers.NewHealthHandler(db.GetPool())
+	instrumentedRepo := db.NewInstrumentedRepository(notesRepo)
+	noteHandler := handlers.NewNoteHandler(instrumentedRepo)
+	shareHandler := handlers.NewShareHandler(repo)
+	auditHandler := handlers.NewAuditHandler(repo)
+	healthHandler := handlers.NewHealthHandler(pool)
+	adminHandler := handlers.NewAdminHandler(database.SlowQueries(), db.NewDiagnostics(pool), repo,
+		advisor.New(pool, advisor.DefaultOptions))
+
+	// Idempotency-Key: respuestas guardadas durante api.idempotency_ttl
+	idempotencyStore := db.NewIdempotencyStore(pool)
+	go purgeIdempotencyKeys(idempotencyStore, cfg.API.IdempotencyPurgeInterval)
 
 	// 4. Configurar router
-	router := gin.Default()
-
-	// Middleware de logging
-	router.Use(gin.Logger())
-
-	// Middleware de recuperación
-	router.Use(gin.Recovery())
-
-	// Rutas
-	api := router.Group("/api/v1")
-	{
-		api.GET("/health", healthHandler.HealthCheck)
-		api.GET("/stats", noteHandler.GetStats)
-
-		// CRUD de notas
-		notes := api.Group("/notes")
-		{
-			notes.POST("", noteHandler.CreateNote)
-			notes.GET("", noteHandler.ListNotes)
-			notes.GET("/batch", noteHandler.GetNotesBatch)
-			notes.GET("/search", noteHandler.SearchNotes)
-			notes.GET("/:id", noteHandler.GetNote)
-			notes.PUT("/:id", noteHandler.UpdateNote)
-			notes.DELETE("/:id", noteHandler.DeleteNote)
-		}
+	router := gin.New()
+
+	// Cache HTTP opcional de ListNotes y SearchNotes (response_cache.enabled); nil no cachea
+	var responseCache *middleware.ResponseCache
+	if cfg.ResponseCache.Enabled {
+		responseCache = middleware.NewResponseCache(router, middleware.ResponseCacheOptions{
+			Size:                 cfg.ResponseCache.Size,
+			MaxAge:               cfg.ResponseCache.MaxAge,
+			StaleWhileRevalidate: cfg.ResponseCache.StaleWhileRevalidate,
+		})
+		noteChangeHandlers = append(noteChangeHandlers, responseCacheInvalidator{responseCache})
 	}
 
-	// 5. Configurar servidor
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8081"
+	// Invalidación entre instancias: escucha los NOTIFY del trigger de notas
+	if len(noteChangeHandlers) > 0 {
+		listenerCtx, stopListener := context.WithCancel(context.Background())
+		defer stopListener()
+		db.NewNoteListener(cfg.Database.URL, logger, noteChangeHandlers...).Start(listenerCtx)
+	}
+
+	// GraphQL opcional (api.graphql). Sus escrituras invalidan el cache de respuestas
+	// vía el listener de notas, igual que las de la API gRPC
+	var graphqlAPI *gqlapi.API
+	if cfg.API.GraphQL {
+		graphqlAPI, err = gqlapi.New(instrumentedRepo, repo, gqlapi.Options{
+			MaxDepth:       cfg.API.GraphQLMaxDepth,
+			MaxParallelism: cfg.API.GraphQLMaxParallelism,
+			Logger:         logger,
+		})
+		if err != nil {
+			fatal("error cargando esquema GraphQL", err)
+		}
 	}
 
+	setupRoutes(router, cfg, logger, routeHandlers{
+		notes:         noteHandler,
+		shares:        shareHandler,
+		audit:         auditHandler,
+		health:        healthHandler,
+		admin:         adminHandler,
+		graphql:       graphqlAPI,
+		idempotency:   idempotencyStore,
+		responseCache: responseCache,
+	})
+
+	// 5. Configurar servidor
+	addr := fmt.Sprintf(":%d", cfg.Server.Port)
 	srv := &http.Server{
-		Addr:         ":" + port,
+		Addr:         addr,
 		Handler:      router,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  30 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
 	// 6. Iniciar servidor en goroutine
 	go func() {
-		log.Printf("Servidor iniciado en http://localhost:%s", port)
+		slog.Info("servidor iniciado", "addr", "http://localhost"+addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Error iniciando servidor:", err)
+			fatal("error iniciando servidor", err)
 		}
 	}()
 
+	// API gRPC opcional (server.grpc_port) sobre el mismo repositorio que REST
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCPort > 0 {
+		grpcAddr := fmt.Sprintf(":%d", cfg.Server.GRPCPort)
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			fatal("error abriendo puerto gRPC", err)
+		}
+
+		grpcServer = grpc.NewServer(grpcapi.ServerOptions(grpcapi.Options{
+			DefaultTenant: cfg.API.DefaultTenant,
+			Logger:        logger,
+		})...)
+		pb.RegisterNotesServiceServer(grpcServer, grpcapi.NewServer(instrumentedRepo))
+		reflection.Register(grpcServer)
+
+		go func() {
+			slog.Info("servidor gRPC iniciado", "addr", grpcAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				fatal("error iniciando servidor gRPC", err)
+			}
+		}()
+	}
+
 	// 7. Esperar señal de terminación
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Apagando servidor...")
+	slog.Info("apagando servidor")
 
 	// 8. Apagado ordenado
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
+	if grpcServer != nil {
+		go func() {
+			<-ctx.Done()
+			grpcServer.Stop()
+		}()
+		grpcServer.GracefulStop()
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Error apagando servidor:", err)
+		fatal("error apagando servidor", err)
+	}
+
+	slog.Info("servidor apagado correctamente")
+}
+
+// fatal registra el error y termina el proceso
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// autoMigrate aplica las migraciones pendientes con un rol dueño del esquema
+func autoMigrate(connString string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		return err
 	}
+	defer pool.Close()
+
+	migrator, err := migrations.New(pool, slog.Default())
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("migraciones al día", "applied", applied)
+	return nil
+}
+
+// purgeIdempotencyKeys elimina periódicamente las claves de idempotencia vencidas
+func purgeIdempotencyKeys(store *db.IdempotencyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		deleted, err := store.PurgeExpired(ctx)
+		cancel()
+
+		if err != nil {
+			slog.Error("error purgando claves de idempotencia", "error", err)
+			continue
+		}
+		if deleted > 0 {
+			slog.Info("claves de idempotencia vencidas eliminadas", "deleted", deleted)
+		}
+	}
+}
+
+// responseCacheInvalidator adapta el cache de respuestas al listener de notas
+type responseCacheInvalidator struct {
+	cache *middleware.ResponseCache
+}
+
+func (i responseCacheInvalidator) NoteChanged(change db.NoteChange) {
+	i.cache.InvalidateTenant(change.TenantID)
+}
 
-	log.Println("Servidor apagado correctamente")
+func (i responseCacheInvalidator) ChangesLost() {
+	i.cache.Purge()
 }
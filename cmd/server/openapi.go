@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/ybotet/notes-api-optimization/internal/gqlapi"
+	"github.com/ybotet/notes-api-optimization/internal/middleware"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// Rutas de la documentación; no forman parte de la especificación
+const (
+	openAPIPath = "/api/v1/openapi.json"
+	apiDocsPath = "/api/v1/docs"
+)
+
+// swaggerInitializer reemplaza el de la distribución de Swagger UI, que apunta al petstore
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "` + openAPIPath + `",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// registerAPIDocs genera la especificación con las rutas ya registradas y la sirve
+// en /api/v1/openapi.json junto con Swagger UI en /api/v1/docs/
+func registerAPIDocs(router *gin.Engine, graphql bool) {
+	doc, err := openAPIDocument(router.Routes(), graphql)
+	if err != nil {
+		slog.Warn("la especificación OpenAPI no coincide con las rutas", "error", err)
+	}
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		fatal("error serializando la especificación OpenAPI", err)
+	}
+
+	router.GET(openAPIPath, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", spec)
+	})
+
+	assets := http.FS(swaggerFiles.FS)
+	router.GET(apiDocsPath+"/*filepath", func(c *gin.Context) {
+		if c.Param("filepath") == "/swagger-initializer.js" {
+			c.Data(http.StatusOK, "application/javascript", []byte(swaggerInitializer))
+			return
+		}
+		c.FileFromFS(c.Param("filepath"), assets)
+	})
+}
+
+// openAPIDocument combina las rutas del router con apiOperations
+func openAPIDocument(routes gin.RoutesInfo, graphql bool) (*openapi.Document, error) {
+	documented := make(gin.RoutesInfo, 0, len(routes))
+	for _, route := range routes {
+		if route.Path != openAPIPath && !strings.HasPrefix(route.Path, apiDocsPath+"/") {
+			documented = append(documented, route)
+		}
+	}
+
+	return openapi.Build(openapi.Spec{
+		Info: openapi.Info{
+			Title:   "Notes API",
+			Version: "1.0.0",
+			Description: "API de notas multi-tenant. Las rutas de notas negocian el formato con Accept " +
+				"(JSON, MessagePack o Protobuf) y todas las respuestas se comprimen según Accept-Encoding.",
+		},
+		Parameters: map[string]openapi.Parameter{
+			"TenantID": {
+				Name: middleware.TenantIDHeader, In: "header",
+				Description: "Workspace del cliente; si no se envía se usa api.default_tenant",
+				Schema:      &openapi.Schema{Type: "string", Pattern: "^[A-Za-z0-9_-]{1,64}$"},
+			},
+			"UserID": {
+				Name: middleware.UserIDHeader, In: "header",
+				Description: "Usuario autenticado por el gateway; sin header la petición es anónima",
+				Schema:      &openapi.Schema{Type: "integer", Format: "int64", Minimum: ptr(1.0)},
+			},
+			"IdempotencyKey": {
+				Name: middleware.IdempotencyKeyHeader, In: "header",
+				Description: "Repite la respuesta guardada si la petición se reintenta con la misma clave",
+				Schema:      &openapi.Schema{Type: "string", MaxLength: ptr(255)},
+			},
+		},
+		SecuritySchemes: map[string]openapi.SecurityScheme{
+			"AdminToken": {Type: "http", Scheme: "bearer", Description: "api.admin_token"},
+		},
+		Operations: apiOperations(graphql),
+	}, documented)
+}
+
+// apiOperations describe cada ruta de setupRoutes por "MÉTODO /ruta"
+func apiOperations(graphql bool) map[string]openapi.Operation {
+	errorBody := openapi.ErrorBody{}
+	admin := []string{"AdminToken"}
+	limit := func(def, max float64) openapi.Parameter {
+		return openapi.Parameter{Name: "limit", Schema: &openapi.Schema{
+			Type: "integer", Minimum: ptr(1.0), Maximum: ptr(max), Default: def,
+		}, Description: "Fuera de rango se usa el valor por defecto"}
+	}
+
+	ops := map[string]openapi.Operation{
+		"GET /metrics": {
+			Summary: "Métricas de Prometheus", Tags: []string{"operación"},
+			Responses: map[int]any{200: openapi.Text{}},
+		},
+		"GET /public/notes/:token": {
+			Summary: "Nota pública en HTML de solo lectura", Tags: []string{"compartir"},
+			Responses: map[int]any{200: openapi.HTML{}, 404: openapi.Text{}, 500: openapi.Text{}},
+		},
+
+		"GET /api/v1/health": {
+			Summary: "Estado de la API y de la base de datos", Tags: []string{"operación"},
+			Responses: map[int]any{200: map[string]any{}, 503: map[string]any{}},
+		},
+		"GET /api/v1/stats": {
+			Summary: "Estadísticas de notas, del pool y de las consultas", Tags: []string{"operación"},
+			Responses: map[int]any{200: map[string]any{}, 500: errorBody},
+		},
+		"GET /api/v1/audit": {
+			Summary: "Log de auditoría", Tags: []string{"administración"}, Security: admin,
+			Query:     models.AuditParams{},
+			Responses: map[int]any{200: models.AuditPage{}, 400: errorBody, 401: errorBody, 403: errorBody, 500: errorBody},
+		},
+
+		"GET /api/v1/admin/slow-queries": {
+			Summary: "Últimas consultas lentas", Tags: []string{"administración"}, Security: admin,
+			QueryParams: []openapi.Parameter{limit(50, 500)},
+			Responses:   map[int]any{200: []models.SlowQuery{}, 401: errorBody, 403: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/admin/slow-queries/:id": {
+			Summary: "Consulta lenta con su plan", Tags: []string{"administración"}, Security: admin,
+			Responses: map[int]any{200: models.SlowQuery{}, 400: errorBody, 401: errorBody, 403: errorBody, 404: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/admin/indexes": {
+			Summary: "Uso y tamaño de los índices", Tags: []string{"administración"}, Security: admin,
+			Responses: map[int]any{200: []models.IndexUsage{}, 401: errorBody, 403: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/admin/tables": {
+			Summary: "Tamaño y actividad de las tablas", Tags: []string{"administración"}, Security: admin,
+			Responses: map[int]any{200: []models.TableSize{}, 401: errorBody, 403: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/admin/statements": {
+			Summary: "Sentencias más costosas (pg_stat_statements)", Tags: []string{"administración"}, Security: admin,
+			QueryParams: []openapi.Parameter{limit(10, 100), {Name: "order_by", Schema: &openapi.Schema{
+				Type: "string", Enum: []any{"total", "mean", "max", "calls", "rows"}, Default: "total",
+			}}},
+			Responses: map[int]any{200: []models.StatementStats{}, 400: errorBody, 401: errorBody, 403: errorBody, 500: errorBody, 503: errorBody},
+		},
+		"POST /api/v1/admin/statements/reset": {
+			Summary: "Reinicia las estadísticas de pg_stat_statements", Tags: []string{"administración"}, Security: admin,
+			Responses: map[int]any{204: nil, 401: errorBody, 403: errorBody, 500: errorBody, 503: errorBody},
+		},
+		"GET /api/v1/admin/sessions": {
+			Summary: "Sesiones activas", Tags: []string{"administración"}, Security: admin,
+			QueryParams: []openapi.Parameter{{Name: "blocked", Description: "Solo las sesiones bloqueadas",
+				Schema: &openapi.Schema{Type: "boolean"}}},
+			Responses: map[int]any{200: []models.Session{}, 401: errorBody, 403: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/admin/cache": {
+			Summary: "Proporción de lecturas servidas desde shared_buffers", Tags: []string{"administración"}, Security: admin,
+			Responses: map[int]any{200: models.CacheHitRatio{}, 401: errorBody, 403: errorBody, 500: errorBody},
+		},
+		"POST /api/v1/admin/explain": {
+			Summary: "EXPLAIN ANALYZE de una consulta del repositorio", Tags: []string{"administración"}, Security: admin,
+			Body:      models.ExplainRequest{},
+			Responses: map[int]any{200: models.ExplainResult{}, 400: errorBody, 401: errorBody, 403: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/admin/advisor": {
+			Summary: "Asesor de índices", Tags: []string{"administración"}, Security: admin,
+			Description: "Con format=sql responde solo el script DDL en text/plain",
+			QueryParams: []openapi.Parameter{{Name: "format", Schema: &openapi.Schema{Type: "string", Enum: []any{"sql"}}}},
+			Responses:   map[int]any{200: models.AdvisorReport{}, 401: errorBody, 403: errorBody, 500: errorBody},
+		},
+
+		"POST /api/v1/notes": {
+			Summary: "Crea una nota", Tags: []string{"notas"},
+			Body:      models.CreateNoteRequest{},
+			Responses: map[int]any{201: models.Note{}, 400: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/notes": {
+			Summary: "Lista notas con paginación por keyset", Tags: []string{"notas"},
+			Query:     models.PaginationParams{},
+			Responses: map[int]any{200: models.NotesPage{}, 400: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/notes/batch": {
+			Summary: "Obtiene varias notas por ID", Tags: []string{"notas"},
+			QueryParams: []openapi.Parameter{{Name: "ids", Required: true, Schema: &openapi.Schema{
+				Type: "array", Items: &openapi.Schema{Type: "integer", Format: "int64"},
+			}}},
+			Responses: map[int]any{200: []models.Note{}, 400: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/notes/search": {
+			Summary: "Busca notas por título", Tags: []string{"notas"},
+			QueryParams: []openapi.Parameter{
+				{Name: "q", Required: true, Schema: &openapi.Schema{Type: "string", MinLength: ptr(1)}},
+				limit(10, 100),
+			},
+			Responses: map[int]any{200: []models.Note{}, 400: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/notes/:id": {
+			Summary: "Obtiene una nota", Tags: []string{"notas"},
+			Responses: map[int]any{200: models.Note{}, 400: errorBody, 404: errorBody, 500: errorBody},
+		},
+		"PUT /api/v1/notes/:id": {
+			Summary: "Actualiza una nota", Tags: []string{"notas"},
+			Body:      models.UpdateNoteRequest{},
+			Responses: map[int]any{200: models.Note{}, 400: errorBody, 404: errorBody, 500: errorBody},
+		},
+		"DELETE /api/v1/notes/:id": {
+			Summary: "Elimina una nota", Tags: []string{"notas"},
+			Responses: map[int]any{204: nil, 400: errorBody, 404: errorBody, 500: errorBody},
+		},
+
+		"GET /api/v1/notes/shared": {
+			Summary: "Notas compartidas con el usuario", Tags: []string{"compartir"},
+			Responses: map[int]any{200: []models.SharedNote{}, 401: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/notes/:id/shares": {
+			Summary: "Usuarios con quienes se compartió la nota", Tags: []string{"compartir"},
+			Responses: map[int]any{200: []models.NoteShare{}, 400: errorBody, 401: errorBody, 404: errorBody, 500: errorBody},
+		},
+		"POST /api/v1/notes/:id/shares": {
+			Summary: "Comparte la nota con otro usuario", Tags: []string{"compartir"},
+			Body:      models.ShareNoteRequest{},
+			Responses: map[int]any{200: models.NoteShare{}, 400: errorBody, 401: errorBody, 404: errorBody, 500: errorBody},
+		},
+		"DELETE /api/v1/notes/:id/shares/:user_id": {
+			Summary: "Deja de compartir la nota con el usuario", Tags: []string{"compartir"},
+			Responses: map[int]any{204: nil, 400: errorBody, 401: errorBody, 404: errorBody, 500: errorBody},
+		},
+		"GET /api/v1/notes/:id/links": {
+			Summary: "Enlaces públicos de la nota", Tags: []string{"compartir"},
+			Responses: map[int]any{200: []models.PublicLink{}, 400: errorBody, 401: errorBody, 404: errorBody, 500: errorBody},
+		},
+		"POST /api/v1/notes/:id/links": {
+			Summary: "Crea un enlace público de solo lectura", Tags: []string{"compartir"},
+			Body: models.CreatePublicLinkRequest{}, BodyOptional: true,
+			Responses: map[int]any{201: models.PublicLink{}, 400: errorBody, 401: errorBody, 404: errorBody, 500: errorBody},
+		},
+		"DELETE /api/v1/notes/:id/links/:link_id": {
+			Summary: "Revoca un enlace público", Tags: []string{"compartir"},
+			Responses: map[int]any{204: nil, 400: errorBody, 401: errorBody, 404: errorBody, 500: errorBody},
+		},
+	}
+
+	if graphql {
+		ops["POST /graphql"] = openapi.Operation{
+			Summary: "Consulta GraphQL", Tags: []string{"graphql"},
+			Description: "Esquema en internal/gqlapi/schema.graphql (con introspección). " +
+				"Los errores de la consulta se devuelven con 200 en errors.",
+			Parameters: []string{"TenantID", "UserID"},
+			Body:       gqlapi.GraphQLRequest{},
+			Responses:  map[int]any{200: map[string]any{}, 400: errorBody},
+		}
+	}
+
+	// Headers comunes de /api/v1: tenant e identidad en todas, Idempotency-Key en los POST
+	for key, op := range ops {
+		method, path, _ := strings.Cut(key, " ")
+		if !strings.HasPrefix(path, "/api/v1/") {
+			continue
+		}
+		op.Parameters = append([]string{"TenantID", "UserID"}, op.Parameters...)
+		if method == http.MethodPost {
+			op.Parameters = append(op.Parameters, "IdempotencyKey")
+		}
+		ops[key] = op
+	}
+	return ops
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
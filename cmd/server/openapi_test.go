@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ybotet/notes-api-optimization/internal/config"
+	"github.com/ybotet/notes-api-optimization/internal/gqlapi"
+	"github.com/ybotet/notes-api-optimization/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testRouter arma el router de producción; los handlers no se ejecutan, así que
+// pueden quedar sin repositorios
+func testRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	graphqlAPI, err := gqlapi.New(nil, nil, gqlapi.Options{})
+	if err != nil {
+		t.Fatalf("esquema GraphQL: %v", err)
+	}
+
+	router := gin.New()
+	setupRoutes(router, config.Default(), slog.New(slog.NewTextHandler(io.Discard, nil)), routeHandlers{graphql: graphqlAPI})
+	return router
+}
+
+// TestOpenAPIMatchesRoutes falla si se agrega una ruta sin describirla en
+// apiOperations o si queda descrita una ruta que ya no existe
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	router := testRouter(t)
+
+	if _, err := openAPIDocument(router.Routes(), true); err != nil {
+		t.Fatalf("la especificación OpenAPI no coincide con las rutas de setupRoutes:\n%v", err)
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	router := testRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, openAPIPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d", openAPIPath, w.Code)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("especificación inválida: %v", err)
+	}
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("openapi = %q, se esperaba %q", doc.OpenAPI, openapi.Version)
+	}
+	if doc.Paths["/api/v1/notes/{id}"]["put"] == nil {
+		t.Errorf("falta PUT /api/v1/notes/{id}")
+	}
+
+	// Las restricciones de binding llegan al esquema
+	title := doc.Components.Schemas["CreateNoteRequest"].Properties["title"]
+	if title == nil || title.MaxLength == nil || *title.MaxLength != 255 || title.MinLength == nil || *title.MinLength != 1 {
+		t.Errorf("CreateNoteRequest.title sin minLength=1 y maxLength=255: %+v", title)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, apiDocsPath+"/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s/ = %d", apiDocsPath, w.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/ybotet/notes-api-optimization/internal/config"
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/gqlapi"
+	"github.com/ybotet/notes-api-optimization/internal/handlers"
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+	"github.com/ybotet/notes-api-optimization/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeHandlers son los handlers y el estado que usan las rutas
+type routeHandlers struct {
+	notes  *handlers.NoteHandler
+	shares *handlers.ShareHandler
+	audit  *handlers.AuditHandler
+	health *handlers.HealthHandler
+	admin  *handlers.AdminHandler
+	// graphql nil deshabilita /graphql
+	graphql       *gqlapi.API
+	idempotency   *db.IdempotencyStore
+	responseCache *middleware.ResponseCache
+}
+
+// setupRoutes registra los middlewares y las rutas. Cada ruta nueva debe
+// describirse en apiOperations (openapi.go): el test de la especificación falla si no.
+func setupRoutes(router *gin.Engine, cfg *config.Config, logger *slog.Logger, h routeHandlers) {
+	// Metadatos de la petición (X-Request-ID, IP del cliente) para logs y auditoría
+	router.Use(middleware.RequestID(), middleware.ClientIP())
+
+	// Span por petición con propagación W3C traceparent
+	router.Use(middleware.Tracing())
+
+	// Middleware de logging estructurado
+	router.Use(middleware.RequestLogger(logger))
+
+	// Métricas HTTP por ruta y código de estado (antes de Recovery para contar los panics como 500)
+	router.Use(middleware.Metrics())
+
+	// Compresión de respuestas (antes de Recovery para comprimir también sus 500)
+	if cfg.Server.Compression {
+		router.Use(middleware.Compression(middleware.CompressionOptions{MinSize: cfg.Server.CompressionMinSize}))
+	}
+
+	// Middleware de recuperación
+	router.Use(middleware.Recovery(logger))
+
+	// Endpoint de Prometheus
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Middleware de identidad (X-User-ID)
+	router.Use(middleware.Identity())
+
+	// GraphQL (api.graphql)
+	if h.graphql != nil {
+		router.POST("/graphql", middleware.Tenant(cfg.API.DefaultTenant), h.graphql.Handler())
+	}
+
+	// Enlaces públicos de solo lectura (sin autenticación)
+	router.GET("/public/notes/:token", h.shares.GetPublicNote)
+
+	// Rutas. Tenant por defecto para clientes que no envían X-Tenant-ID
+	// (api.default_tenant vacío obliga a enviar el header)
+	api := router.Group("/api/v1",
+		middleware.Tenant(cfg.API.DefaultTenant),
+		middleware.Idempotency(h.idempotency, cfg.API.IdempotencyTTL, cfg.API.IdempotencyLease),
+		h.responseCache.Invalidate())
+	{
+		api.GET("/health", h.health.HealthCheck)
+		api.GET("/stats", h.notes.GetStats)
+		api.GET("/audit", middleware.RequireAdmin(cfg.API.AdminToken), h.audit.ListAuditEntries)
+
+		// Diagnóstico (requiere api.admin_token)
+		admin := api.Group("/admin", middleware.RequireAdmin(cfg.API.AdminToken))
+		{
+			admin.GET("/slow-queries", h.admin.ListSlowQueries)
+			admin.GET("/slow-queries/:id", h.admin.GetSlowQuery)
+			admin.GET("/indexes", h.admin.IndexUsage)
+			admin.GET("/tables", h.admin.TableSizes)
+			admin.GET("/statements", h.admin.TopStatements)
+			admin.POST("/statements/reset", h.admin.ResetStatements)
+			admin.GET("/sessions", h.admin.Sessions)
+			admin.GET("/cache", h.admin.CacheHitRatio)
+			admin.POST("/explain", h.admin.ExplainQuery)
+			admin.GET("/advisor", h.admin.IndexAdvisor)
+		}
+
+		// CRUD de notas
+		notes := api.Group("/notes")
+		{
+			notes.POST("", h.notes.CreateNote)
+			notes.GET("", h.responseCache.Handler(), h.notes.ListNotes)
+			notes.GET("/batch", h.notes.GetNotesBatch)
+			notes.GET("/search", h.responseCache.Handler(), h.notes.SearchNotes)
+			notes.GET("/:id", h.notes.GetNote)
+			notes.PUT("/:id", h.notes.UpdateNote)
+			notes.DELETE("/:id", h.notes.DeleteNote)
+
+			// Colaboración: notas compartidas y enlaces públicos
+			notes.GET("/shared", middleware.RequireUser(), h.shares.ListSharedWithMe)
+
+			owned := notes.Group("/:id", middleware.RequireUser())
+			{
+				owned.GET("/shares", h.shares.ListShares)
+				owned.POST("/shares", h.shares.ShareNote)
+				owned.DELETE("/shares/:user_id", h.shares.UnshareNote)
+				owned.GET("/links", h.shares.ListPublicLinks)
+				owned.POST("/links", h.shares.CreatePublicLink)
+				owned.DELETE("/links/:link_id", h.shares.RevokePublicLink)
+			}
+		}
+	}
+
+	// Especificación OpenAPI y Swagger UI, fuera del grupo /api/v1 para no exigir tenant
+	registerAPIDocs(router, h.graphql != nil)
+}
@@ -0,0 +1,216 @@
+// Package advisor revisa las estadísticas de PostgreSQL y sugiere cambios de índices:
+// índices sin uso, duplicados o cubiertos por otro (con su DROP INDEX), y tablas con
+// muchos seq scans (sin DDL: solo las sentencias de pg_stat_statements que las leen).
+package advisor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Options ajusta los umbrales del asesor
+type Options struct {
+	// MinSeqScanRows es el mínimo de filas vivas para considerar los seq scans de una tabla
+	MinSeqScanRows int64
+	// MinSeqScanRatio es la proporción mínima de seq scans sobre el total de scans
+	MinSeqScanRatio float64
+	// StatementsPerTable es la cantidad de sentencias de pg_stat_statements que se adjuntan
+	StatementsPerTable int
+}
+
+// DefaultOptions son los umbrales usados por el servidor y el comando advisor
+var DefaultOptions = Options{
+	MinSeqScanRows:     10000,
+	MinSeqScanRatio:    0.5,
+	StatementsPerTable: 3,
+}
+
+type Advisor struct {
+	pool *pgxpool.Pool
+	opts Options
+}
+
+func New(pool *pgxpool.Pool, opts Options) *Advisor {
+	return &Advisor{pool: pool, opts: opts}
+}
+
+// indexStats son los datos de un índice necesarios para las reglas
+type indexStats struct {
+	schema     string
+	table      string
+	name       string
+	method     string
+	columns    []string // columnas clave, con " DESC" si corresponde
+	include    int      // cantidad de columnas INCLUDE
+	predicate  string
+	unique     bool
+	constraint bool // respalda una PRIMARY KEY, UNIQUE o EXCLUDE
+	valid      bool
+	scans      int64
+	sizeBytes  int64
+}
+
+type tableStats struct {
+	schema     string
+	table      string
+	liveTuples int64
+	seqScans   int64
+	seqRead    int64
+	idxScans   int64
+	sizeBytes  int64
+}
+
+// Report recolecta las estadísticas y aplica las reglas
+func (a *Advisor) Report(ctx context.Context) (*models.AdvisorReport, error) {
+	report := &models.AdvisorReport{GeneratedAt: time.Now().UTC()}
+
+	err := a.pool.QueryRow(ctx, `
+        SELECT stats_reset FROM pg_stat_database WHERE datname = current_database()
+    `).Scan(&report.StatsReset)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando stats_reset: %w", err)
+	}
+
+	indexes, err := a.indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := a.tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Findings = append(report.Findings, unusedIndexes(indexes)...)
+	report.Findings = append(report.Findings, duplicateIndexes(indexes)...)
+	report.Findings = append(report.Findings, redundantIndexes(indexes)...)
+
+	seqScans := heavySeqScans(tables, a.opts)
+	report.StatementsAvailable = a.attachStatements(ctx, seqScans)
+	report.Findings = append(report.Findings, seqScans...)
+
+	if report.Findings == nil {
+		report.Findings = []models.AdvisorFinding{}
+	}
+
+	return report, nil
+}
+
+func (a *Advisor) indexes(ctx context.Context) ([]indexStats, error) {
+	query := `
+        SELECT s.schemaname, s.relname, s.indexrelname, am.amname,
+               ARRAY(
+                   SELECT pg_get_indexdef(i.indexrelid, k, true)
+                          || CASE WHEN i.indoption[k - 1] & 1 = 1 THEN ' DESC' ELSE '' END
+                   FROM generate_series(1, i.indnkeyatts) AS k
+                   ORDER BY k
+               ),
+               i.indnatts - i.indnkeyatts,
+               COALESCE(pg_get_expr(i.indpred, i.indrelid, true), ''),
+               i.indisunique,
+               EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid),
+               i.indisvalid,
+               s.idx_scan,
+               pg_relation_size(s.indexrelid)
+        FROM pg_stat_user_indexes s
+        JOIN pg_index i ON i.indexrelid = s.indexrelid
+        JOIN pg_class c ON c.oid = s.indexrelid
+        JOIN pg_am am ON am.oid = c.relam
+        ORDER BY s.schemaname, s.relname, s.indexrelname
+    `
+
+	rows, err := a.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando índices: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []indexStats
+	for rows.Next() {
+		var idx indexStats
+		if err := rows.Scan(&idx.schema, &idx.table, &idx.name, &idx.method,
+			&idx.columns, &idx.include, &idx.predicate,
+			&idx.unique, &idx.constraint, &idx.valid,
+			&idx.scans, &idx.sizeBytes); err != nil {
+			return nil, fmt.Errorf("error escaneando índice: %w", err)
+		}
+		indexes = append(indexes, idx)
+	}
+
+	return indexes, rows.Err()
+}
+
+func (a *Advisor) tables(ctx context.Context) ([]tableStats, error) {
+	query := `
+        SELECT schemaname, relname, n_live_tup, seq_scan, seq_tup_read,
+               COALESCE(idx_scan, 0), pg_total_relation_size(relid)
+        FROM pg_stat_user_tables
+        ORDER BY schemaname, relname
+    `
+
+	rows, err := a.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando tablas: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []tableStats
+	for rows.Next() {
+		var t tableStats
+		if err := rows.Scan(&t.schema, &t.table, &t.liveTuples, &t.seqScans, &t.seqRead,
+			&t.idxScans, &t.sizeBytes); err != nil {
+			return nil, fmt.Errorf("error escaneando tabla: %w", err)
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, rows.Err()
+}
+
+// attachStatements agrega a cada hallazgo de seq scan las sentencias más costosas que
+// mencionan la tabla. El nombre se escapa antes de usarlo en la expresión regular
+// (una tabla "notes.v2" no debe coincidir con "notesXv2"). Devuelve false si
+// pg_stat_statements no está disponible.
+func (a *Advisor) attachStatements(ctx context.Context, findings []models.AdvisorFinding) bool {
+	var available bool
+	err := a.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')`).Scan(&available)
+	if err != nil || !available {
+		return false
+	}
+
+	query := `
+        SELECT LEFT(query, 1000)
+        FROM pg_stat_statements
+        WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+          AND query ~* ('\m' || regexp_replace($1::text, '([^[:alnum:]_])', '\\\1', 'g') || '\M')
+          AND query !~* '^\s*(EXPLAIN|BEGIN|COMMIT|ROLLBACK|SET|SELECT set_config)'
+        ORDER BY total_exec_time DESC
+        LIMIT $2
+    `
+
+	for i := range findings {
+		rows, err := a.pool.Query(ctx, query, findings[i].Table, a.opts.StatementsPerTable)
+		if err != nil {
+			// shared_preload_libraries sin pg_stat_statements: la extensión existe pero la vista falla
+			return false
+		}
+		for rows.Next() {
+			var statement string
+			if err := rows.Scan(&statement); err == nil {
+				findings[i].Statements = append(findings[i].Statements, statement)
+			}
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return false
+		}
+	}
+
+	return true
+}
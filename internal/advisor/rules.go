@@ -0,0 +1,167 @@
+package advisor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// unusedIndexes marca los índices que nunca se usaron y no respaldan una restricción
+func unusedIndexes(indexes []indexStats) []models.AdvisorFinding {
+	var findings []models.AdvisorFinding
+	for _, idx := range indexes {
+		if idx.scans > 0 || idx.constraint || idx.unique || !idx.valid {
+			continue
+		}
+		findings = append(findings, models.AdvisorFinding{
+			Kind:      models.FindingUnusedIndex,
+			Table:     idx.table,
+			Index:     idx.name,
+			Detail:    "el índice no registra scans desde el último reinicio de estadísticas",
+			SizeBytes: idx.sizeBytes,
+			DDL:       dropIndex(idx),
+		})
+	}
+	return findings
+}
+
+// duplicateIndexes marca los índices con las mismas columnas, método y predicado que otro
+func duplicateIndexes(indexes []indexStats) []models.AdvisorFinding {
+	var findings []models.AdvisorFinding
+	for i, a := range indexes {
+		for _, b := range indexes[i+1:] {
+			if !sameTable(a, b) || a.method != b.method || a.predicate != b.predicate ||
+				!slices.Equal(a.columns, b.columns) {
+				continue
+			}
+
+			keep, drop := a, b
+			if !droppable(drop) || (droppable(keep) && keep.include < drop.include) {
+				keep, drop = b, a
+			}
+			if !droppable(drop) {
+				continue
+			}
+
+			findings = append(findings, models.AdvisorFinding{
+				Kind:      models.FindingDuplicateIndex,
+				Table:     drop.table,
+				Index:     drop.name,
+				Detail:    fmt.Sprintf("mismas columnas que %s (%s)", keep.name, strings.Join(keep.columns, ", ")),
+				SizeBytes: drop.sizeBytes,
+				DDL:       dropIndex(drop),
+			})
+		}
+	}
+	return findings
+}
+
+// redundantIndexes marca los índices btree cuyas columnas son un prefijo de otro índice
+// btree de la misma tabla: cualquier búsqueda que use el primero puede usar el segundo.
+// Un índice de una sola dirección invertida también queda cubierto (scan hacia atrás).
+func redundantIndexes(indexes []indexStats) []models.AdvisorFinding {
+	var findings []models.AdvisorFinding
+	for _, short := range indexes {
+		if short.method != "btree" || !droppable(short) || short.include > 0 {
+			continue
+		}
+		for _, long := range indexes {
+			if long.name == short.name || !sameTable(short, long) || long.method != "btree" ||
+				long.predicate != short.predicate || !long.valid ||
+				len(long.columns) <= len(short.columns) {
+				continue
+			}
+
+			prefix := long.columns[:len(short.columns)]
+			if !slices.Equal(short.columns, prefix) && !slices.Equal(short.columns, reversed(prefix)) {
+				continue
+			}
+
+			findings = append(findings, models.AdvisorFinding{
+				Kind:  models.FindingRedundantIndex,
+				Table: short.table,
+				Index: short.name,
+				Detail: fmt.Sprintf("(%s) es prefijo de %s (%s)",
+					strings.Join(short.columns, ", "), long.name, strings.Join(long.columns, ", ")),
+				SizeBytes: short.sizeBytes,
+				DDL:       dropIndex(short),
+			})
+			break
+		}
+	}
+	return findings
+}
+
+// heavySeqScans marca las tablas grandes que se leen mayormente con seq scans.
+// No sugiere DDL: el índice adecuado depende de los filtros de las consultas,
+// que se adjuntan desde pg_stat_statements para revisarlas con EXPLAIN.
+func heavySeqScans(tables []tableStats, opts Options) []models.AdvisorFinding {
+	var findings []models.AdvisorFinding
+	for _, t := range tables {
+		total := t.seqScans + t.idxScans
+		if t.liveTuples < opts.MinSeqScanRows || total == 0 {
+			continue
+		}
+
+		ratio := float64(t.seqScans) / float64(total)
+		if ratio < opts.MinSeqScanRatio {
+			continue
+		}
+
+		findings = append(findings, models.AdvisorFinding{
+			Kind:  models.FindingSeqScan,
+			Table: t.table,
+			Detail: fmt.Sprintf("%.0f%% de los scans son secuenciales (%d seq scans, %d filas leídas en promedio, %d filas vivas); "+
+				"sin DDL sugerido: revisar con EXPLAIN las sentencias adjuntas",
+				ratio*100, t.seqScans, t.seqRead/max(t.seqScans, 1), t.liveTuples),
+			SizeBytes: t.sizeBytes,
+		})
+	}
+	return findings
+}
+
+// droppable indica si el índice puede eliminarse sin perder una restricción
+func droppable(idx indexStats) bool {
+	return !idx.constraint && !idx.unique
+}
+
+func sameTable(a, b indexStats) bool {
+	return a.schema == b.schema && a.table == b.table
+}
+
+// reversed invierte la dirección de todas las columnas
+func reversed(columns []string) []string {
+	out := make([]string, len(columns))
+	for i, c := range columns {
+		if trimmed, ok := strings.CutSuffix(c, " DESC"); ok {
+			out[i] = trimmed
+		} else {
+			out[i] = c + " DESC"
+		}
+	}
+	return out
+}
+
+// dropIndex cita siempre los identificadores: así también valen los nombres que
+// empiezan con un dígito o coinciden con palabras reservadas (order, user...)
+func dropIndex(idx indexStats) string {
+	return fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s;", pgx.Identifier{idx.schema, idx.name}.Sanitize())
+}
+
+// Script reúne el DDL sugerido del reporte, sin repetir sentencias
+func Script(report *models.AdvisorReport) string {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for _, f := range report.Findings {
+		if f.DDL == "" || seen[f.DDL] {
+			continue
+		}
+		seen[f.DDL] = true
+		fmt.Fprintf(&b, "-- %s: %s\n%s\n", f.Kind, f.Detail, f.DDL)
+	}
+	return b.String()
+}
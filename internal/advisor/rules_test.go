@@ -0,0 +1,213 @@
+package advisor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+)
+
+// btree arma un índice btree válido de public.notes
+func btree(name string, columns ...string) indexStats {
+	return indexStats{schema: "public", table: "notes", name: name, method: "btree",
+		columns: columns, valid: true, scans: 1, sizeBytes: 8192}
+}
+
+// constraint marca el índice como respaldo de una PRIMARY KEY o UNIQUE
+func constraint(idx indexStats) indexStats {
+	idx.unique, idx.constraint = true, true
+	return idx
+}
+
+func findingIndexes(findings []models.AdvisorFinding) []string {
+	names := make([]string, len(findings))
+	for i, f := range findings {
+		names[i] = f.Index
+	}
+	return names
+}
+
+// El caso de init.sql: idx_notes_created_at (created_at DESC) es prefijo de
+// idx_notes_created_id (created_at DESC, id DESC)
+func TestRedundantIndexesInitSQL(t *testing.T) {
+	indexes := []indexStats{
+		constraint(btree("notes_pkey", "id")),
+		btree("idx_notes_created_id", "created_at DESC", "id DESC"),
+		btree("idx_notes_created_at", "created_at DESC"),
+		btree("idx_notes_tenant_created_id", "tenant_id", "created_at DESC", "id DESC"),
+	}
+
+	findings := redundantIndexes(indexes)
+	if len(findings) != 1 {
+		t.Fatalf("hallazgos = %v, se esperaba solo idx_notes_created_at", findingIndexes(findings))
+	}
+
+	f := findings[0]
+	if f.Kind != models.FindingRedundantIndex || f.Index != "idx_notes_created_at" || f.Table != "notes" {
+		t.Errorf("hallazgo = %+v", f)
+	}
+	if !strings.Contains(f.Detail, "idx_notes_created_id") {
+		t.Errorf("el detalle no nombra el índice que lo cubre: %q", f.Detail)
+	}
+	if f.DDL != `DROP INDEX CONCURRENTLY IF EXISTS "public"."idx_notes_created_at";` {
+		t.Errorf("DDL = %q", f.DDL)
+	}
+}
+
+func TestRedundantIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		indexes []indexStats
+		want    []string
+	}{
+		{
+			name:    "dirección invertida (scan hacia atrás)",
+			indexes: []indexStats{btree("a", "created_at"), btree("ab", "created_at DESC", "id DESC")},
+			want:    []string{"a"},
+		},
+		{
+			name:    "dirección mixta no cubierta",
+			indexes: []indexStats{btree("ab", "created_at", "id"), btree("abc", "created_at DESC", "id", "title")},
+			want:    nil,
+		},
+		{
+			name:    "no es prefijo",
+			indexes: []indexStats{btree("b", "id"), btree("ab", "created_at", "id")},
+			want:    nil,
+		},
+		{
+			name: "otra tabla",
+			indexes: []indexStats{btree("a", "created_at"), func() indexStats {
+				idx := btree("ab", "created_at", "id")
+				idx.table = "audit_log"
+				return idx
+			}()},
+			want: nil,
+		},
+		{
+			name:    "el corto respalda una restricción",
+			indexes: []indexStats{constraint(btree("a_key", "created_at")), btree("ab", "created_at", "id")},
+			want:    nil,
+		},
+		{
+			name: "predicado distinto",
+			indexes: []indexStats{func() indexStats {
+				idx := btree("a", "owner_id")
+				idx.predicate = "owner_id IS NOT NULL"
+				return idx
+			}(), btree("ab", "owner_id", "id")},
+			want: nil,
+		},
+		{
+			name: "el largo no es btree",
+			indexes: []indexStats{btree("a", "title"), func() indexStats {
+				idx := btree("ab", "title", "id")
+				idx.method = "gin"
+				return idx
+			}()},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findingIndexes(redundantIndexes(tt.indexes))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("redundantes = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateIndexes(t *testing.T) {
+	pkey := constraint(btree("notes_pkey", "id"))
+	withInclude := btree("idx_notes_id_incl", "id")
+	withInclude.include = 1
+
+	tests := []struct {
+		name    string
+		indexes []indexStats
+		want    []string
+	}{
+		{"se conserva la restricción", []indexStats{btree("idx_notes_id", "id"), pkey}, []string{"idx_notes_id"}},
+		{"se conserva el de más columnas INCLUDE", []indexStats{withInclude, btree("idx_notes_id", "id")}, []string{"idx_notes_id"}},
+		{"columnas distintas", []indexStats{btree("a", "id"), btree("b", "title")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findingIndexes(duplicateIndexes(tt.indexes))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("duplicados = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnusedIndexes(t *testing.T) {
+	unused := btree("idx_unused", "title")
+	unused.scans = 0
+	pkey := constraint(btree("notes_pkey", "id"))
+	pkey.scans = 0
+	invalid := btree("idx_invalid", "content")
+	invalid.scans, invalid.valid = 0, false
+
+	got := findingIndexes(unusedIndexes([]indexStats{unused, pkey, invalid, btree("idx_used", "id")}))
+	if strings.Join(got, ",") != "idx_unused" {
+		t.Errorf("sin uso = %v, se esperaba [idx_unused]", got)
+	}
+}
+
+func TestHeavySeqScans(t *testing.T) {
+	tables := []tableStats{
+		{table: "notes", liveTuples: 50000, seqScans: 90, idxScans: 10, seqRead: 900000},
+		{table: "small", liveTuples: 100, seqScans: 100},
+		{table: "indexed", liveTuples: 50000, seqScans: 10, idxScans: 90},
+		{table: "unread", liveTuples: 50000},
+	}
+
+	findings := heavySeqScans(tables, DefaultOptions)
+	if len(findings) != 1 || findings[0].Table != "notes" {
+		t.Fatalf("hallazgos = %+v, se esperaba solo notes", findings)
+	}
+	if !strings.Contains(findings[0].Detail, "90%") || !strings.Contains(findings[0].Detail, "sin DDL") {
+		t.Errorf("detalle = %q", findings[0].Detail)
+	}
+	if findings[0].DDL != "" {
+		t.Errorf("DDL = %q, los seq scans no sugieren DDL", findings[0].DDL)
+	}
+}
+
+func TestScript(t *testing.T) {
+	report := &models.AdvisorReport{Findings: []models.AdvisorFinding{
+		{Kind: models.FindingDuplicateIndex, Detail: "a", DDL: "DROP INDEX CONCURRENTLY IF EXISTS public.x;"},
+		{Kind: models.FindingRedundantIndex, Detail: "b", DDL: "DROP INDEX CONCURRENTLY IF EXISTS public.x;"},
+		{Kind: models.FindingSeqScan, Detail: "c"},
+	}}
+
+	script := Script(report)
+	if n := strings.Count(script, "DROP INDEX"); n != 1 {
+		t.Errorf("el script repite el DDL (%d veces):\n%s", n, script)
+	}
+	if strings.Contains(script, models.FindingSeqScan) {
+		t.Errorf("el script incluye hallazgos sin DDL:\n%s", script)
+	}
+}
+
+func TestDropIndexQuotesIdentifiers(t *testing.T) {
+	tests := map[string]string{
+		"idx_notes":   `"public"."idx_notes"`,
+		"Idx":         `"public"."Idx"`,
+		"order":       `"public"."order"`,
+		"user":        `"public"."user"`,
+		"1idx":        `"public"."1idx"`,
+		`weird"name`:  `"public"."weird""name"`,
+		"con espacio": `"public"."con espacio"`,
+	}
+	for name, want := range tests {
+		idx := btree(name, "id")
+		if got := dropIndex(idx); got != "DROP INDEX CONCURRENTLY IF EXISTS "+want+";" {
+			t.Errorf("dropIndex(%q) = %s", name, got)
+		}
+	}
+}
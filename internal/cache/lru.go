@@ -0,0 +1,117 @@
+// Package cache contiene caches en memoria del proceso.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU es un cache acotado a capacity entradas que expulsa la menos usada.
+// Cada entrada vence ttl después de guardarse (ttl <= 0: no vence).
+// Es seguro para uso concurrente.
+type LRU[K comparable, V any] struct {
+	capacity int
+	ttl      time.Duration
+	onEvict  func(K)
+
+	mu    sync.Mutex
+	items map[K]*list.Element
+	order *list.List // frente: más reciente
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// NewLRU crea el cache; onEvict (opcional) se llama, con el lock tomado, al expulsar
+// una entrada por capacidad
+func NewLRU[K comparable, V any](capacity int, ttl time.Duration, onEvict func(K)) *LRU[K, V] {
+	return &LRU[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		onEvict:  onEvict,
+		items:    make(map[K]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// Get retorna el valor si existe y no venció
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	el, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	e := el.Value.(*entry[K, V])
+	if c.ttl > 0 && time.Now().After(e.expiresAt) {
+		c.removeElement(el)
+		return zero, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set guarda o reemplaza el valor
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.removeElement(oldest)
+		if c.onEvict != nil {
+			c.onEvict(oldest.Value.(*entry[K, V]).key)
+		}
+	}
+}
+
+// Delete elimina la entrada (si existe)
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Purge vacía el cache
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[K]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+// Len retorna la cantidad de entradas (incluidas las vencidas aún no expulsadas)
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).key)
+}
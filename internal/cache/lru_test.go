@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictionOrder(t *testing.T) {
+	var evicted []string
+	c := NewLRU[string, int](3, 0, func(key string) { evicted = append(evicted, key) })
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+
+	// Get y Set vuelven a poner la entrada al frente: la menos usada pasa a ser b
+	c.Get("a")
+	c.Set("c", 30)
+	c.Set("d", 4)
+
+	if !slices.Equal(evicted, []string{"b"}) {
+		t.Fatalf("expulsadas = %v, se esperaba [b]", evicted)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b sigue en el cache")
+	}
+	for key, want := range map[string]int{"a": 1, "c": 30, "d": 4} {
+		if got, ok := c.Get(key); !ok || got != want {
+			t.Errorf("Get(%s) = %d, %v; se esperaba %d", key, got, ok, want)
+		}
+	}
+
+	// Orden actual (más reciente primero): d, c, a
+	c.Set("e", 5)
+	c.Set("f", 6)
+	if !slices.Equal(evicted, []string{"b", "a", "c"}) {
+		t.Errorf("expulsadas = %v, se esperaba [b a c]", evicted)
+	}
+	if c.Len() != 3 {
+		t.Errorf("Len = %d, se esperaba 3", c.Len())
+	}
+}
+
+func TestLRUDeleteAndPurge(t *testing.T) {
+	evictions := 0
+	c := NewLRU[int, string](10, 0, func(int) { evictions++ })
+	for i := range 5 {
+		c.Set(i, fmt.Sprint(i))
+	}
+
+	c.Delete(2)
+	c.Delete(42)
+	if _, ok := c.Get(2); ok || c.Len() != 4 {
+		t.Errorf("después de Delete: Len = %d, Get(2) = %v", c.Len(), ok)
+	}
+
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("después de Purge: Len = %d", c.Len())
+	}
+	// onEvict solo cuenta las expulsiones por capacidad
+	if evictions != 0 {
+		t.Errorf("onEvict se llamó %d veces", evictions)
+	}
+}
+
+func TestLRUTTL(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	c := NewLRU[string, int](10, ttl, nil)
+
+	c.Set("a", 1)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("la entrada vence antes del TTL")
+	}
+
+	time.Sleep(ttl / 2)
+	c.Set("b", 2)
+	time.Sleep(ttl/2 + 10*time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("a no venció")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("b venció antes de tiempo")
+	}
+	// Get expulsa la entrada vencida
+	if c.Len() != 1 {
+		t.Errorf("Len = %d, se esperaba 1", c.Len())
+	}
+
+	// Set renueva el vencimiento
+	time.Sleep(ttl / 2)
+	c.Set("b", 3)
+	time.Sleep(ttl/2 + 10*time.Millisecond)
+	if got, ok := c.Get("b"); !ok || got != 3 {
+		t.Errorf("Get(b) = %d, %v; Set no renovó el vencimiento", got, ok)
+	}
+}
+
+func TestLRUWithoutTTL(t *testing.T) {
+	c := NewLRU[string, int](10, 0, nil)
+	c.Set("a", 1)
+	time.Sleep(10 * time.Millisecond)
+	if _, ok := c.Get("a"); !ok {
+		t.Error("con ttl <= 0 la entrada no debe vencer")
+	}
+}
+
+// Uso concurrente: correr con -race
+func TestLRUConcurrent(t *testing.T) {
+	const capacity = 64
+	c := NewLRU[int, int](capacity, time.Minute, nil)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 2000 {
+				key := (g*31 + i) % 200
+				switch i % 4 {
+				case 0, 1:
+					c.Set(key, key)
+				case 2:
+					if v, ok := c.Get(key); ok && v != key {
+						t.Errorf("Get(%d) = %d", key, v)
+					}
+				case 3:
+					c.Delete(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := c.Len(); n > capacity {
+		t.Errorf("Len = %d supera la capacidad %d", n, capacity)
+	}
+}
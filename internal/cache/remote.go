@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// generationRefresh es cada cuánto se relee la generación compartida: un Purge de
+// otra instancia tarda a lo sumo esto en verse
+const generationRefresh = time.Second
+
+// lockPollInterval es la frecuencia con la que se espera la recarga de otra instancia
+const lockPollInterval = 20 * time.Millisecond
+
+// RemoteOptions configura un Remote
+type RemoteOptions[K comparable] struct {
+	// Prefix agrupa las claves de este cache (p. ej. "notes")
+	Prefix string
+	// Version es la versión del formato serializado: subirla al cambiar V hace que
+	// se ignoren las entradas escritas con el formato anterior
+	Version int
+	TTL     time.Duration
+	// Key convierte la clave en su parte final de la clave RESP
+	Key func(K) string
+	// LockTTL es la vigencia del lock de recarga; LockWait, cuánto espera otra
+	// instancia a que el dueño del lock guarde el valor antes de cargarlo ella misma
+	LockTTL  time.Duration
+	LockWait time.Duration
+}
+
+// Remote es un Store compartido entre instancias sobre un servidor RESP. Los valores
+// se guardan en JSON bajo claves versionadas: prefijo, versión del formato y una
+// generación compartida que Purge incrementa (las claves viejas vencen por TTL).
+//
+// Para evitar estampidas, ante un fallo GetOrLoad toma un lock por clave (SET NX):
+// solo el dueño carga de la fuente; el resto espera el valor hasta LockWait.
+type Remote[K comparable, V any] struct {
+	client *RESPClient
+	opts   RemoteOptions[K]
+
+	mu           sync.Mutex
+	generation   int64
+	generationAt time.Time
+}
+
+func NewRemote[K comparable, V any](client *RESPClient, opts RemoteOptions[K]) *Remote[K, V] {
+	return &Remote[K, V]{client: client, opts: opts}
+}
+
+func (r *Remote[K, V]) Get(ctx context.Context, key K) (V, bool, error) {
+	k, err := r.key(ctx, key)
+	if err != nil {
+		var zero V
+		return zero, false, err
+	}
+	return r.get(ctx, k)
+}
+
+func (r *Remote[K, V]) GetOrLoad(ctx context.Context, key K, load Loader[V]) (V, bool, error) {
+	k, err := r.key(ctx, key)
+	if err != nil {
+		// Cache caído: se lee de la fuente sin guardar
+		return load(ctx)
+	}
+
+	if value, ok, err := r.get(ctx, k); err == nil && ok {
+		return value, true, nil
+	}
+
+	lockKey := k + ":lock"
+	_, err = r.client.Do(ctx, "SET", lockKey, "1", "NX", "PX", strconv.FormatInt(r.opts.LockTTL.Milliseconds(), 10))
+	switch {
+	case err == nil:
+		// Lock tomado: esta instancia carga. Si la carga supera LockTTL el lock ya
+		// puede ser de otra instancia y no se borra.
+		start := time.Now()
+		defer func() {
+			if time.Since(start) < r.opts.LockTTL {
+				r.client.Do(context.WithoutCancel(ctx), "DEL", lockKey)
+			}
+		}()
+		// El dueño anterior pudo guardar el valor entre el GET y el SET NX
+		if value, ok, err := r.get(ctx, k); err == nil && ok {
+			return value, true, nil
+		}
+		return r.loadAndSet(ctx, k, load)
+
+	case errors.Is(err, ErrNil):
+		// Otra instancia está cargando la clave
+		if value, ok := r.wait(ctx, k); ok {
+			return value, true, nil
+		}
+		return r.loadAndSet(ctx, k, load)
+
+	default:
+		return load(ctx)
+	}
+}
+
+func (r *Remote[K, V]) Set(ctx context.Context, key K, value V) error {
+	k, err := r.key(ctx, key)
+	if err != nil {
+		return err
+	}
+	return r.set(ctx, k, value)
+}
+
+func (r *Remote[K, V]) Delete(ctx context.Context, key K) error {
+	k, err := r.key(ctx, key)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.Do(ctx, "DEL", k)
+	return err
+}
+
+// Purge incrementa la generación compartida: todas las instancias dejan de ver las
+// entradas actuales (a lo sumo generationRefresh después)
+func (r *Remote[K, V]) Purge(ctx context.Context) error {
+	reply, err := r.client.Do(ctx, "INCR", r.opts.Prefix+":generation")
+	if err != nil {
+		return fmt.Errorf("error incrementando generación del cache: %w", err)
+	}
+	generation, _ := reply.(int64)
+
+	r.mu.Lock()
+	r.generation, r.generationAt = generation, time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Remote[K, V]) get(ctx context.Context, k string) (V, bool, error) {
+	var value V
+	reply, err := r.client.Do(ctx, "GET", k)
+	if errors.Is(err, ErrNil) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+
+	data, _ := reply.(string)
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return value, false, fmt.Errorf("error deserializando %s: %w", k, err)
+	}
+	return value, true, nil
+}
+
+func (r *Remote[K, V]) set(ctx context.Context, k string, value V) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error serializando %s: %w", k, err)
+	}
+	_, err = r.client.Do(ctx, "SET", k, string(data), "PX", strconv.FormatInt(r.opts.TTL.Milliseconds(), 10))
+	return err
+}
+
+func (r *Remote[K, V]) loadAndSet(ctx context.Context, k string, load Loader[V]) (V, bool, error) {
+	value, ok, err := load(ctx)
+	if err != nil || !ok {
+		return value, ok, err
+	}
+	// Un error al guardar no invalida el valor ya leído
+	_ = r.set(ctx, k, value)
+	return value, true, nil
+}
+
+// wait espera a que el dueño del lock guarde el valor
+func (r *Remote[K, V]) wait(ctx context.Context, k string) (V, bool) {
+	var zero V
+	timer := time.NewTimer(r.opts.LockWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return zero, false
+		case <-timer.C:
+			return zero, false
+		case <-ticker.C:
+			if value, ok, err := r.get(ctx, k); err != nil {
+				return zero, false
+			} else if ok {
+				return value, true
+			}
+		}
+	}
+}
+
+// key arma la clave versionada: prefijo:v<versión>:g<generación>:<clave>
+func (r *Remote[K, V]) key(ctx context.Context, key K) (string, error) {
+	generation, err := r.currentGeneration(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:v%d:g%d:%s", r.opts.Prefix, r.opts.Version, generation, r.opts.Key(key)), nil
+}
+
+func (r *Remote[K, V]) currentGeneration(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	if time.Since(r.generationAt) < generationRefresh {
+		defer r.mu.Unlock()
+		return r.generation, nil
+	}
+	r.mu.Unlock()
+
+	var generation int64
+	reply, err := r.client.Do(ctx, "GET", r.opts.Prefix+":generation")
+	switch {
+	case errors.Is(err, ErrNil):
+		// Sin Purge previo: generación 0
+	case err != nil:
+		return 0, fmt.Errorf("error leyendo generación del cache: %w", err)
+	default:
+		s, _ := reply.(string)
+		if generation, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return 0, fmt.Errorf("generación del cache inválida %q: %w", s, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.generation, r.generationAt = generation, time.Now()
+	r.mu.Unlock()
+	return generation, nil
+}
@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testNote struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+// newTestClient usa TEST_REDIS_URL si está definido; si no, levanta un servidor
+// RESP mínimo en proceso con los comandos que usa Remote
+func newTestClient(t *testing.T) *RESPClient {
+	t.Helper()
+
+	rawURL := os.Getenv("TEST_REDIS_URL")
+	if rawURL == "" {
+		rawURL = "redis://" + startStandIn(t)
+	}
+
+	client, err := NewRESPClient(context.Background(), rawURL, DefaultRESPOptions)
+	if err != nil {
+		t.Fatalf("error conectando: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func newTestRemote(client *RESPClient, prefix string, version int) *Remote[int64, testNote] {
+	return NewRemote[int64, testNote](client, RemoteOptions[int64]{
+		Prefix:   prefix,
+		Version:  version,
+		TTL:      time.Minute,
+		Key:      func(id int64) string { return strconv.FormatInt(id, 10) },
+		LockTTL:  2 * time.Second,
+		LockWait: time.Second,
+	})
+}
+
+// testPrefix aísla las claves de cada prueba en un Redis real
+func testPrefix(t *testing.T) string {
+	return fmt.Sprintf("test:%s:%d", t.Name(), time.Now().UnixNano())
+}
+
+func TestRemoteRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRemote(newTestClient(t), testPrefix(t), 1)
+
+	if _, ok, err := r.Get(ctx, 1); err != nil || ok {
+		t.Fatalf("Get sin valor: ok=%v err=%v", ok, err)
+	}
+
+	want := testNote{ID: 1, Title: "hola"}
+	if err := r.Set(ctx, 1, want); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, ok, err := r.Get(ctx, 1)
+	if err != nil || !ok || got != want {
+		t.Fatalf("Get = %+v, %v, %v; se esperaba %+v", got, ok, err, want)
+	}
+
+	if err := r.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok, _ := r.Get(ctx, 1); ok {
+		t.Fatal("la clave sigue existiendo después de Delete")
+	}
+}
+
+func TestRemoteKeyVersioning(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	prefix := testPrefix(t)
+
+	v1 := newTestRemote(client, prefix, 1)
+	if err := v1.Set(ctx, 1, testNote{ID: 1}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// Otra versión del formato no ve las entradas de la anterior
+	if _, ok, _ := newTestRemote(client, prefix, 2).Get(ctx, 1); ok {
+		t.Fatal("la versión 2 leyó una entrada de la versión 1")
+	}
+
+	// Purge cambia la generación compartida
+	other := newTestRemote(client, prefix, 1)
+	if _, ok, _ := other.Get(ctx, 1); !ok {
+		t.Fatal("otra instancia no ve la entrada antes de Purge")
+	}
+	if err := v1.Purge(ctx); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if _, ok, _ := v1.Get(ctx, 1); ok {
+		t.Fatal("la entrada sigue visible después de Purge")
+	}
+	if _, ok, _ := newTestRemote(client, prefix, 1).Get(ctx, 1); ok {
+		t.Fatal("una instancia nueva ve la entrada después de Purge")
+	}
+}
+
+func TestRemoteGetOrLoadStampede(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t)
+	prefix := testPrefix(t)
+
+	// Varias "instancias" sobre el mismo servidor
+	nodes := []*Remote[int64, testNote]{
+		newTestRemote(client, prefix, 1),
+		newTestRemote(client, prefix, 1),
+		newTestRemote(client, prefix, 1),
+	}
+
+	var loads atomic.Int32
+	load := func(context.Context) (testNote, bool, error) {
+		loads.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		return testNote{ID: 7, Title: "cargada"}, true, nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(node *Remote[int64, testNote]) {
+			defer wg.Done()
+			got, ok, err := node.GetOrLoad(ctx, 7, load)
+			if err != nil || !ok || got.Title != "cargada" {
+				errs <- fmt.Errorf("GetOrLoad = %+v, %v, %v", got, ok, err)
+			}
+		}(nodes[i%len(nodes)])
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("la fuente se consultó %d veces, se esperaba 1", n)
+	}
+}
+
+func TestRemoteGetOrLoadNotFound(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRemote(newTestClient(t), testPrefix(t), 1)
+
+	_, ok, err := r.GetOrLoad(ctx, 1, func(context.Context) (testNote, bool, error) {
+		return testNote{}, false, nil
+	})
+	if err != nil || ok {
+		t.Fatalf("GetOrLoad = ok=%v err=%v; se esperaba no encontrado", ok, err)
+	}
+
+	loadErr := errors.New("fallo")
+	_, _, err = r.GetOrLoad(ctx, 2, func(context.Context) (testNote, bool, error) {
+		return testNote{}, false, loadErr
+	})
+	if !errors.Is(err, loadErr) {
+		t.Fatalf("GetOrLoad err = %v; se esperaba %v", err, loadErr)
+	}
+}
+
+// startStandIn levanta un servidor compatible con RESP que implementa PING, GET,
+// SET (con NX y PX), DEL e INCR sobre un mapa en memoria
+func startStandIn(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error escuchando: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &standIn{data: make(map[string]standInValue)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+type standIn struct {
+	mu   sync.Mutex
+	data map[string]standInValue
+}
+
+type standInValue struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (s *standIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		if _, err := conn.Write([]byte(s.exec(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *standIn) exec(args []string) string {
+	if len(args) == 0 {
+		return "-ERR comando vacío\r\n"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+
+	case "GET":
+		v, ok := s.lookup(args[1])
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+
+	case "SET":
+		var nx bool
+		var ttl time.Duration
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				ms, _ := strconv.Atoi(args[i+1])
+				ttl = time.Duration(ms) * time.Millisecond
+				i++
+			}
+		}
+		if _, exists := s.lookup(args[1]); nx && exists {
+			return "$-1\r\n"
+		}
+		v := standInValue{value: args[2]}
+		if ttl > 0 {
+			v.expiresAt = time.Now().Add(ttl)
+		}
+		s.data[args[1]] = v
+		return "+OK\r\n"
+
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lookup(key); ok {
+				delete(s.data, key)
+				n++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+
+	case "INCR":
+		v, _ := s.lookup(args[1])
+		n, _ := strconv.ParseInt(v, 10, 64)
+		n++
+		s.data[args[1]] = standInValue{value: strconv.FormatInt(n, 10)}
+		return fmt.Sprintf(":%d\r\n", n)
+
+	default:
+		return "-ERR comando desconocido '" + args[0] + "'\r\n"
+	}
+}
+
+func (s *standIn) lookup(key string) (string, bool) {
+	v, ok := s.data[key]
+	if !ok {
+		return "", false
+	}
+	if !v.expiresAt.IsZero() && time.Now().After(v.expiresAt) {
+		delete(s.data, key)
+		return "", false
+	}
+	return v.value, true
+}
@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNil es la respuesta nula de RESP (clave inexistente en GET)
+var ErrNil = errors.New("respuesta nula")
+
+// RESPError es un error devuelto por el servidor (-ERR ...); la conexión sigue sana
+type RESPError string
+
+func (e RESPError) Error() string { return string(e) }
+
+// RESPOptions configura el cliente
+type RESPOptions struct {
+	PoolSize    int
+	DialTimeout time.Duration
+	// IOTimeout acota cada comando si el contexto no tiene deadline
+	IOTimeout time.Duration
+}
+
+// DefaultRESPOptions son valores razonables para un Redis en la misma red
+var DefaultRESPOptions = RESPOptions{
+	PoolSize:    10,
+	DialTimeout: 2 * time.Second,
+	IOTimeout:   500 * time.Millisecond,
+}
+
+// RESPClient es un cliente mínimo del protocolo de Redis (RESP2) con un pool de
+// conexiones. Sirve contra Redis o cualquier servidor compatible.
+type RESPClient struct {
+	addr     string
+	username string
+	password string
+	database int
+	opts     RESPOptions
+	idle     chan *respConn
+}
+
+type respConn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// NewRESPClient crea el cliente para una URL redis://[usuario:clave@]host:puerto[/db]
+// y verifica la conexión con PING
+func NewRESPClient(ctx context.Context, rawURL string, opts RESPOptions) (*RESPClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parseando URL de cache: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("esquema de cache no soportado: %q", u.Scheme)
+	}
+
+	c := &RESPClient{
+		addr: u.Host,
+		opts: opts,
+		idle: make(chan *respConn, opts.PoolSize),
+	}
+	if !strings.Contains(c.addr, ":") {
+		c.addr += ":6379"
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		if c.database, err = strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("base de cache inválida %q: %w", path, err)
+		}
+	}
+
+	if _, err := c.Do(ctx, "PING"); err != nil {
+		return nil, fmt.Errorf("error conectando al cache: %w", err)
+	}
+	return c, nil
+}
+
+// Do ejecuta un comando. Las respuestas son string (simple o bulk), int64 o
+// []interface{}; la respuesta nula retorna ErrNil.
+func (c *RESPClient) Do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, c.opts.IOTimeout, args...)
+	var respErr RESPError
+	if err != nil && !errors.Is(err, ErrNil) && !errors.As(err, &respErr) {
+		// Error de red o de protocolo: la conexión queda en un estado desconocido
+		conn.Close()
+		return nil, err
+	}
+
+	c.put(conn)
+	return reply, err
+}
+
+// Close cierra las conexiones inactivas
+func (c *RESPClient) Close() error {
+	for {
+		select {
+		case conn := <-c.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *RESPClient) get(ctx context.Context) (*respConn, error) {
+	select {
+	case conn := <-c.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.opts.DialTimeout}
+	nc, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &respConn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := conn.do(ctx, c.opts.IOTimeout, args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error autenticando en el cache: %w", err)
+		}
+	}
+	if c.database != 0 {
+		if _, err := conn.do(ctx, c.opts.IOTimeout, "SELECT", strconv.Itoa(c.database)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error seleccionando base del cache: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *RESPClient) put(conn *respConn) {
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (conn *respConn) do(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// Los comandos se envían como array de bulk strings
+	fmt.Fprintf(conn.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(conn.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := conn.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return readReply(conn.r)
+}
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("respuesta RESP inválida: %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, RESPError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("longitud RESP inválida: %w", err)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("longitud RESP inválida: %w", err)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readReply(r)
+			if err != nil && !errors.Is(err, ErrNil) {
+				return nil, err
+			}
+			items[i] = item // nil para elementos nulos (MGET)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("tipo RESP desconocido: %q", kind)
+	}
+}
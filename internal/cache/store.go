@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+)
+
+// Store es la interfaz común de los caches: en proceso (Local) o compartido entre
+// instancias (Remote). Los errores de un Store externo no deben cortar la lectura:
+// quien lo usa cae a la fuente original.
+type Store[K comparable, V any] interface {
+	Get(ctx context.Context, key K) (V, bool, error)
+	// GetOrLoad retorna el valor cacheado o lo obtiene con load y lo guarda
+	GetOrLoad(ctx context.Context, key K, load Loader[V]) (V, bool, error)
+	Set(ctx context.Context, key K, value V) error
+	Delete(ctx context.Context, key K) error
+	// Purge invalida todas las entradas
+	Purge(ctx context.Context) error
+}
+
+// Loader obtiene el valor de la fuente original; ok=false indica que no existe
+// (no se guarda nada)
+type Loader[V any] func(ctx context.Context) (value V, ok bool, err error)
+
+// Local adapta un LRU a Store
+type Local[K comparable, V any] struct {
+	lru *LRU[K, V]
+}
+
+func NewLocal[K comparable, V any](lru *LRU[K, V]) *Local[K, V] {
+	return &Local[K, V]{lru: lru}
+}
+
+func (l *Local[K, V]) Get(_ context.Context, key K) (V, bool, error) {
+	value, ok := l.lru.Get(key)
+	return value, ok, nil
+}
+
+// GetOrLoad no coalesce las cargas simultáneas: en el proceso eso lo resuelve
+// la capa que está debajo (db.CoalescingRepository)
+func (l *Local[K, V]) GetOrLoad(ctx context.Context, key K, load Loader[V]) (V, bool, error) {
+	if value, ok := l.lru.Get(key); ok {
+		return value, true, nil
+	}
+
+	value, ok, err := load(ctx)
+	if err != nil || !ok {
+		return value, ok, err
+	}
+	l.lru.Set(key, value)
+	return value, true, nil
+}
+
+func (l *Local[K, V]) Set(_ context.Context, key K, value V) error {
+	l.lru.Set(key, value)
+	return nil
+}
+
+func (l *Local[K, V]) Delete(_ context.Context, key K) error {
+	l.lru.Delete(key)
+	return nil
+}
+
+func (l *Local[K, V]) Purge(context.Context) error {
+	l.lru.Purge()
+	return nil
+}
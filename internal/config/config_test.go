@@ -0,0 +1,200 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const yamlConfig = `
+server:
+  port: 8100
+  read_timeout: 20s
+database:
+  max_conns: 30
+  min_conns: 3
+log:
+  level: warn
+`
+
+const tomlConfig = `
+[server]
+port = 8100
+read_timeout = "20s"
+
+[database]
+max_conns = 30
+min_conns = 3
+replica_urls = ["postgres://r1/notes", "postgres://r2/notes"]
+
+[log]
+level = "warn"
+`
+
+// Precedencia: defaults < archivo < entorno < flags
+func TestLoadPrecedence(t *testing.T) {
+	for _, file := range []struct{ name, content string }{
+		{"config.yaml", yamlConfig},
+		{"config.toml", tomlConfig},
+	} {
+		t.Run(file.name, func(t *testing.T) {
+			path := writeFile(t, file.name, file.content)
+			t.Setenv("CONFIG_FILE", path)
+			t.Setenv("DB_MAX_CONNS", "40")
+			t.Setenv("LOG_LEVEL", "error")
+			t.Setenv("TRACES_OTLP_ENDPOINT", "collector:4318")
+			t.Setenv("TRACES_OTLP_INSECURE", "true")
+
+			cfg, err := Load([]string{"-log.level", "debug"})
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+
+			tests := []struct {
+				key    string
+				got    any
+				want   any
+				source string
+			}{
+				{"server.write_timeout", cfg.Server.WriteTimeout, 10 * time.Second, "default"},
+				{"server.port", cfg.Server.Port, 8100, "file"},
+				{"server.read_timeout", cfg.Server.ReadTimeout, 20 * time.Second, "file"},
+				{"database.min_conns", cfg.Database.MinConns, int32(3), "file"},
+				{"database.max_conns", cfg.Database.MaxConns, int32(40), "env"},
+				{"log.level", cfg.Log.Level, "debug", "flag"},
+				{"tracing.otlp_endpoint", cfg.Tracing.OTLPEndpoint, "collector:4318", "env"},
+				{"tracing.otlp_insecure", cfg.Tracing.OTLPInsecure, true, "env"},
+			}
+			for _, tt := range tests {
+				if tt.got != tt.want {
+					t.Errorf("%s = %v, se esperaba %v", tt.key, tt.got, tt.want)
+				}
+				if src := cfg.source(tt.key); src != tt.source {
+					t.Errorf("origen de %s = %s, se esperaba %s", tt.key, src, tt.source)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTOMLList(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.toml", tomlConfig))
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := strings.Join(cfg.Database.ReplicaURLs, ","); got != "postgres://r1/notes,postgres://r2/notes" {
+		t.Errorf("database.replica_urls = %q", got)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	for _, file := range []struct{ name, content string }{
+		{"config.yaml", "server:\n  prot: 8100\n"},
+		{"config.toml", "[server]\nprot = 8100\n"},
+	} {
+		t.Run(file.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", writeFile(t, file.name, file.content))
+			if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "prot") {
+				t.Errorf("Load = %v, se esperaba un error por la clave desconocida", err)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidValue(t *testing.T) {
+	t.Setenv("DB_MAX_CONNS", "muchas")
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "DB_MAX_CONNS") {
+		t.Errorf("Load = %v, se esperaba un error que nombre DB_MAX_CONNS", err)
+	}
+
+	if _, err := Load([]string{"-server.port", "x"}); err == nil {
+		t.Error("Load con -server.port inválido no falló")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("la configuración por defecto no es válida: %v", err)
+	}
+
+	cfg := Default()
+	cfg.Server.Port = 70000
+	cfg.Database.MinConns = 50
+	cfg.Log.Format = "xml"
+	cfg.Cache.Backend = "memcached"
+	cfg.API.GraphQLMaxParallelism = 0
+	cfg.Tracing.OTLPEndpoint = "http://collector:4318"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate no detectó los errores")
+	}
+	// Todos los errores juntos, no solo el primero
+	for _, want := range []string{"server.port", "database.min_conns", "log.format", "cache.backend", "api.graphql_max_parallelism", "tracing.otlp_endpoint"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("falta %s en el error: %v", want, err)
+		}
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	cfg := Default()
+	cfg.Database.URL = "postgres://notes_app:s3cret@db:5432/notes"
+	cfg.Database.MigrationsURL = "host=db user=owner password=s3cret dbname=notes"
+	cfg.Database.ReplicaURLs = []string{"postgres://r:s3cret@r1/notes", "postgres://r:s3cret@r2/notes"}
+	cfg.API.AdminToken = "s3cret"
+	cfg.Cache.RedisURL = "redis://:s3cret@cache:6379/0"
+
+	effective := cfg.Effective()
+	for key, value := range effective {
+		if strings.Contains(value, "s3cret") {
+			t.Errorf("%s muestra el secreto: %s", key, value)
+		}
+	}
+
+	tests := map[string]string{
+		"database.url":            "postgres://notes_app:xxxxx@db:5432/notes",
+		"database.migrations_url": "host=db user=owner password=xxxxx dbname=notes",
+		"database.replica_urls":   "postgres://r:xxxxx@r1/notes,postgres://r:xxxxx@r2/notes",
+		"api.admin_token":         "********",
+		"cache.redis_url":         "redis://:xxxxx@cache:6379/0",
+	}
+	for key, want := range tests {
+		if got := effective[key]; got != want {
+			t.Errorf("%s = %q, se esperaba %q", key, got, want)
+		}
+	}
+
+	// Un secreto que parece URL no se muestra: solo las cadenas de conexión
+	// de PostgreSQL y Redis conservan su estructura
+	cfg.API.AdminToken = "admin:s3cret"
+	cfg.Database.URL = "https://user:s3cret@db/notes"
+	cfg.Database.MigrationsURL = "mysql://owner@db/notes?token=s3cret"
+	effective = cfg.Effective()
+	for _, key := range []string{"api.admin_token", "database.url", "database.migrations_url"} {
+		if got := effective[key]; got != "********" {
+			t.Errorf("%s = %q, se esperaba ********", key, got)
+		}
+	}
+
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cret") {
+		t.Errorf("Print muestra un secreto:\n%s", out.String())
+	}
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// option enlaza un campo de Config con su clave, variable de entorno y flag
+type option struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	// connString indica un secreto que es una cadena de conexión: se muestra sin la contraseña
+	connString bool
+	// allowEmpty permite que una variable definida pero vacía pise el valor (DEFAULT_TENANT="")
+	allowEmpty bool
+	// list indica que el valor es una lista separada por comas
+	list bool
+	set  func(string) error
+	get  func() string
+}
+
+func (o option) display() string {
+	v := o.get()
+	if !o.secret {
+		return v
+	}
+	if !o.connString {
+		return redact(v)
+	}
+	if o.list {
+		parts := strings.Split(v, ",")
+		for i, p := range parts {
+			parts[i] = redactConnString(p)
+		}
+		return strings.Join(parts, ",")
+	}
+	return redactConnString(v)
+}
+
+func (c *Config) options() []option {
+	return []option{
+		intOption("server.port", "PORT", "puerto HTTP", &c.Server.Port),
+		durationOption("server.read_timeout", "HTTP_READ_TIMEOUT", "timeout de lectura", &c.Server.ReadTimeout),
+		durationOption("server.write_timeout", "HTTP_WRITE_TIMEOUT", "timeout de escritura", &c.Server.WriteTimeout),
+		durationOption("server.idle_timeout", "HTTP_IDLE_TIMEOUT", "timeout de conexiones inactivas", &c.Server.IdleTimeout),
+		durationOption("server.shutdown_timeout", "HTTP_SHUTDOWN_TIMEOUT", "tiempo máximo de apagado ordenado", &c.Server.ShutdownTimeout),
+		boolOption("server.compression", "HTTP_COMPRESSION", "comprimir respuestas (gzip, br o zstd)", &c.Server.Compression),
+		intOption("server.compression_min_size", "HTTP_COMPRESSION_MIN_SIZE", "bytes mínimos de cuerpo para comprimir", &c.Server.CompressionMinSize),
+		intOption("server.grpc_port", "GRPC_PORT", "puerto de la API gRPC (0 la desactiva)", &c.Server.GRPCPort),
+
+		connString(stringOption("database.url", "DATABASE_URL", "cadena de conexión de la API (rol sin privilegios)", &c.Database.URL)),
+		connString(stringOption("database.migrations_url", "MIGRATIONS_DATABASE_URL", "cadena de conexión para migraciones (dueño del esquema)", &c.Database.MigrationsURL)),
+		boolOption("database.auto_migrate", "AUTO_MIGRATE", "aplicar migraciones al arrancar", &c.Database.AutoMigrate),
+		intOption("database.max_conns", "DB_MAX_CONNS", "máximo de conexiones del pool", &c.Database.MaxConns),
+		intOption("database.min_conns", "DB_MIN_CONNS", "mínimo de conexiones en reposo", &c.Database.MinConns),
+		durationOption("database.max_conn_lifetime", "DB_MAX_CONN_LIFETIME", "vida máxima de una conexión", &c.Database.MaxConnLifetime),
+		durationOption("database.max_conn_idle_time", "DB_MAX_CONN_IDLE_TIME", "tiempo máximo inactiva", &c.Database.MaxConnIdleTime),
+		durationOption("database.health_check_period", "DB_HEALTH_CHECK_PERIOD", "frecuencia del health check del pool", &c.Database.HealthCheckPeriod),
+		intOption("database.statement_cache_capacity", "DB_STATEMENT_CACHE_CAPACITY", "sentencias preparadas en cache por conexión", &c.Database.StatementCacheCapacity),
+		durationOption("database.slow_query_threshold", "SLOW_QUERY_THRESHOLD", "umbral de consulta lenta (0 deshabilita)", &c.Database.SlowQueryThreshold),
+		boolOption("database.slow_query_explain", "SLOW_QUERY_EXPLAIN", "capturar EXPLAIN de las consultas lentas", &c.Database.SlowQueryExplain),
+		connString(stringListOption("database.replica_urls", "DB_REPLICA_URLS", "cadenas de conexión de réplicas de lectura, separadas por comas", &c.Database.ReplicaURLs)),
+		durationOption("database.replica_check_interval", "DB_REPLICA_CHECK_INTERVAL", "frecuencia del health check de réplicas", &c.Database.ReplicaCheckInterval),
+		durationOption("database.replica_max_lag", "DB_REPLICA_MAX_LAG", "retraso máximo antes de expulsar una réplica (0 no lo verifica)", &c.Database.ReplicaMaxLag),
+		durationOption("database.read_your_writes", "DB_READ_YOUR_WRITES", "lecturas a la primaria tras escribir (0 deshabilita)", &c.Database.ReadYourWrites),
+
+		stringOption("log.level", "LOG_LEVEL", "nivel de log: debug, info, warn o error", &c.Log.Level),
+		stringOption("log.format", "LOG_FORMAT", "formato de log: json o text", &c.Log.Format),
+
+		stringOption("tracing.exporter", "TRACES_EXPORTER", "exportador de trazas: none, otlp o file", &c.Tracing.Exporter),
+		stringOption("tracing.otlp_endpoint", "TRACES_OTLP_ENDPOINT", "host:puerto del collector OTLP (vacío usa OTEL_EXPORTER_OTLP_ENDPOINT)", &c.Tracing.OTLPEndpoint),
+		boolOption("tracing.otlp_insecure", "TRACES_OTLP_INSECURE", "enviar las trazas OTLP sin TLS", &c.Tracing.OTLPInsecure),
+		stringOption("tracing.file", "TRACES_FILE", "archivo del exportador file", &c.Tracing.File),
+		floatOption("tracing.sample_ratio", "TRACES_SAMPLE_RATIO", "proporción de trazas muestreadas", &c.Tracing.SampleRatio),
+
+		allowEmpty(stringOption("api.default_tenant", "DEFAULT_TENANT", "tenant sin X-Tenant-ID (vacío lo exige)", &c.API.DefaultTenant)),
+		secret(stringOption("api.admin_token", "ADMIN_TOKEN", "token Bearer de los endpoints de administración", &c.API.AdminToken)),
+		durationOption("api.idempotency_ttl", "IDEMPOTENCY_TTL", "vigencia de las claves de idempotencia", &c.API.IdempotencyTTL),
+		durationOption("api.idempotency_lease", "IDEMPOTENCY_LEASE", "duración de una reserva en curso antes de que otra petición pueda tomarla", &c.API.IdempotencyLease),
+		durationOption("api.idempotency_purge_interval", "IDEMPOTENCY_PURGE_INTERVAL", "frecuencia de purga de claves vencidas", &c.API.IdempotencyPurgeInterval),
+		boolOption("api.graphql", "GRAPHQL_ENABLED", "habilitar POST /graphql", &c.API.GraphQL),
+		intOption("api.graphql_max_depth", "GRAPHQL_MAX_DEPTH", "profundidad máxima de las consultas GraphQL (0 sin límite)", &c.API.GraphQLMaxDepth),
+		intOption("api.graphql_max_parallelism", "GRAPHQL_MAX_PARALLELISM", "resolvers GraphQL en paralelo por consulta", &c.API.GraphQLMaxParallelism),
+
+		boolOption("cache.enabled", "CACHE_ENABLED", "cachear GetNoteByID y GetNotesBatch", &c.Cache.Enabled),
+		stringOption("cache.backend", "CACHE_BACKEND", "backend del cache: memory o redis", &c.Cache.Backend),
+		connString(stringOption("cache.redis_url", "CACHE_REDIS_URL", "URL del servidor RESP (Redis o compatible)", &c.Cache.RedisURL)),
+		intOption("cache.size", "CACHE_SIZE", "máximo de notas en cache (backend memory)", &c.Cache.Size),
+		durationOption("cache.ttl", "CACHE_TTL", "vigencia de cada nota en cache", &c.Cache.TTL),
+		boolOption("cache.coalesce", "CACHE_COALESCE", "compartir la consulta entre lecturas idénticas simultáneas", &c.Cache.Coalesce),
+
+		boolOption("response_cache.enabled", "RESPONSE_CACHE_ENABLED", "cachear las respuestas de ListNotes y SearchNotes", &c.ResponseCache.Enabled),
+		intOption("response_cache.size", "RESPONSE_CACHE_SIZE", "máximo de respuestas en cache", &c.ResponseCache.Size),
+		durationOption("response_cache.max_age", "RESPONSE_CACHE_MAX_AGE", "tiempo durante el que una respuesta es fresca", &c.ResponseCache.MaxAge),
+		durationOption("response_cache.stale_while_revalidate", "RESPONSE_CACHE_STALE_WHILE_REVALIDATE", "tiempo extra sirviendo una respuesta vencida mientras se revalida", &c.ResponseCache.StaleWhileRevalidate),
+	}
+}
+
+func secret(o option) option {
+	o.secret = true
+	return o
+}
+
+// connString marca un secreto que es una URL o DSN de PostgreSQL o Redis
+func connString(o option) option {
+	o.secret, o.connString = true, true
+	return o
+}
+
+func allowEmpty(o option) option {
+	o.allowEmpty = true
+	return o
+}
+
+func stringOption(key, env, usage string, p *string) option {
+	return option{key: key, env: env, usage: usage,
+		set: func(v string) error { *p = v; return nil },
+		get: func() string { return *p },
+	}
+}
+
+func stringListOption(key, env, usage string, p *[]string) option {
+	return option{key: key, env: env, usage: usage, list: true,
+		set: func(v string) error {
+			*p = nil
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*p = append(*p, item)
+				}
+			}
+			return nil
+		},
+		get: func() string { return strings.Join(*p, ",") },
+	}
+}
+
+func intOption[T int | int32](key, env, usage string, p *T) option {
+	return option{key: key, env: env, usage: usage,
+		set: func(v string) error {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				return err
+			}
+			*p = T(n)
+			return nil
+		},
+		get: func() string { return strconv.FormatInt(int64(*p), 10) },
+	}
+}
+
+func floatOption(key, env, usage string, p *float64) option {
+	return option{key: key, env: env, usage: usage,
+		set: func(v string) error {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return err
+			}
+			*p = f
+			return nil
+		},
+		get: func() string { return strconv.FormatFloat(*p, 'g', -1, 64) },
+	}
+}
+
+func boolOption(key, env, usage string, p *bool) option {
+	return option{key: key, env: env, usage: usage,
+		set: func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			*p = b
+			return nil
+		},
+		get: func() string { return strconv.FormatBool(*p) },
+	}
+}
+
+func durationOption(key, env, usage string, p *time.Duration) option {
+	return option{key: key, env: env, usage: usage,
+		set: func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			*p = d
+			return nil
+		},
+		get: func() string { return p.String() },
+	}
+}
+
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('[^']*'|\S+)`)
+
+// connSchemes son los esquemas de URL que se muestran sin la contraseña
+var connSchemes = []string{"postgres", "postgresql", "redis", "rediss"}
+
+// redact oculta un secreto entero
+func redact(v string) string {
+	if v == "" {
+		return ""
+	}
+	return "********"
+}
+
+// redactConnString oculta la contraseña de una URL o DSN de PostgreSQL o Redis;
+// cualquier otro valor se oculta entero
+func redactConnString(v string) string {
+	if v == "" {
+		return ""
+	}
+
+	if u, err := url.Parse(v); err == nil && slices.Contains(connSchemes, strings.ToLower(u.Scheme)) {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		q := u.Query()
+		if q.Has("password") {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+
+	if !strings.Contains(v, "://") && dsnPassword.MatchString(v) {
+		return dsnPassword.ReplaceAllString(v, "${1}xxxxx")
+	}
+
+	return redact(v)
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AuditRepository consulta el log de auditoría (append-only) del tenant
+type AuditRepository interface {
+	ListAuditEntries(ctx context.Context, params models.AuditParams) (*models.AuditPage, error)
+}
+
+// recordAudit registra una operación sobre una nota dentro de la transacción de la escritura,
+// así el log y el cambio se confirman (o se descartan) juntos
+func recordAudit(ctx context.Context, tx pgx.Tx, action string, noteID int64, before, after *models.Note) error {
+	query := `
+        INSERT INTO audit_log (actor_id, action, note_id, before_hash, after_hash, request_id, client_ip)
+        VALUES (NULLIF($1, 0), $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, '')::inet)
+    `
+
+	_, err := tx.Exec(ctx, query,
+		reqctx.UserID(ctx), action, noteID, noteHash(before), noteHash(after),
+		reqctx.RequestID(ctx), reqctx.ClientIP(ctx))
+	if err != nil {
+		return fmt.Errorf("error registrando auditoría: %w", err)
+	}
+
+	return nil
+}
+
+// noteHash calcula el SHA-256 de la representación JSON de la nota (nil si no hay nota)
+func noteHash(note *models.Note) *string {
+	if note == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(note)
+	if err != nil {
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	return &hash
+}
+
+// ListAuditEntries lista el log de auditoría con paginación por keyset sobre id (más recientes primero)
+func (r *PostgresRepository) ListAuditEntries(ctx context.Context, params models.AuditParams) (*models.AuditPage, error) {
+	if params.Limit == 0 {
+		params.Limit = 50
+	}
+
+	var conditions []string
+	var args []interface{}
+	addCondition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if params.CursorID > 0 {
+		addCondition("id < $%d", params.CursorID)
+	}
+	if params.NoteID > 0 {
+		addCondition("note_id = $%d", params.NoteID)
+	}
+	if params.ActorID > 0 {
+		addCondition("actor_id = $%d", params.ActorID)
+	}
+	if params.Action != "" {
+		addCondition("action = $%d", params.Action)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, params.Limit+1) // +1 para saber si hay más páginas
+	query := fmt.Sprintf(`
+        SELECT id, actor_id, action, note_id, before_hash, after_hash,
+               COALESCE(request_id, ''), COALESCE(host(client_ip), ''), created_at
+        FROM audit_log
+        %s
+        ORDER BY id DESC
+        LIMIT $%d
+    `, where, len(args))
+
+	entries := []models.AuditEntry{}
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("error listando auditoría: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e models.AuditEntry
+			if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.NoteID, &e.BeforeHash, &e.AfterHash,
+				&e.RequestID, &e.ClientIP, &e.CreatedAt); err != nil {
+				return fmt.Errorf("error escaneando auditoría: %w", err)
+			}
+			entries = append(entries, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hasNext := len(entries) > params.Limit
+	if hasNext {
+		entries = entries[:params.Limit]
+	}
+
+	var nextCursor string
+	if hasNext && len(entries) > 0 {
+		nextCursor = fmt.Sprintf("cursor_id=%d", entries[len(entries)-1].ID)
+	}
+
+	return &models.AuditPage{
+		Entries:  entries,
+		NextPage: hasNext,
+		Cursor:   nextCursor,
+	}, nil
+}
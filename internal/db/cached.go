@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/cache"
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+)
+
+// noteCacheName es la etiqueta de las métricas del cache de notas
+const noteCacheName = "notes"
+
+// noteCacheVersion versiona el formato de models.Note en el cache compartido:
+// subirla al cambiar el struct para no leer entradas con el formato anterior
+const noteCacheVersion = 1
+
+// NoteKey identifica una nota dentro de su tenant
+type NoteKey struct {
+	TenantID string
+	ID       int64
+}
+
+// NewLocalNoteCache crea el cache de notas en proceso (un LRU por instancia)
+func NewLocalNoteCache(size int, ttl time.Duration) cache.Store[NoteKey, models.Note] {
+	return cache.NewLocal(cache.NewLRU[NoteKey, models.Note](size, ttl, func(NoteKey) {
+		metrics.CacheEvictions.WithLabelValues(noteCacheName).Inc()
+	}))
+}
+
+// NewRemoteNoteCache crea el cache de notas compartido entre instancias sobre RESP
+func NewRemoteNoteCache(client *cache.RESPClient, ttl time.Duration) cache.Store[NoteKey, models.Note] {
+	return cache.NewRemote[NoteKey, models.Note](client, cache.RemoteOptions[NoteKey]{
+		Prefix:   noteCacheName,
+		Version:  noteCacheVersion,
+		TTL:      ttl,
+		Key:      func(k NoteKey) string { return fmt.Sprintf("%s:%d", k.TenantID, k.ID) },
+		LockTTL:  5 * time.Second,
+		LockWait: 250 * time.Millisecond,
+	})
+}
+
+// CachedRepository cachea GetNoteByID y GetNotesBatch en un cache.Store: en
+// proceso (NewLocalNoteCache) o compartido (NewRemoteNoteCache).
+//
+// Se cachea la fila de la nota por (tenant, id), pero solo se sirve desde el cache
+// a quien la ve sin depender de note_shares: notas sin dueño o del propio usuario.
+// Las notas compartidas siempre se leen de la base, así retirar una compartición
+// tiene efecto inmediato. UpdateNote y DeleteNote invalidan la entrada; los cambios
+// hechos por otras instancias llegan vía NoteListener (NoteChanged y ChangesLost).
+// Un error del cache no corta la lectura: se lee de la base.
+type CachedRepository struct {
+	Repository
+	notes cache.Store[NoteKey, models.Note]
+
+	// generation se incrementa en cada invalidación: una lectura que empezó antes
+	// no guarda su resultado, que podría ser anterior a la escritura
+	generation atomic.Uint64
+}
+
+func NewCachedRepository(next Repository, notes cache.Store[NoteKey, models.Note]) *CachedRepository {
+	return &CachedRepository{Repository: next, notes: notes}
+}
+
+// GetNoteByID obtiene la nota del cache si el usuario puede verla, si no de la base
+func (r *CachedRepository) GetNoteByID(ctx context.Context, id int64) (*models.Note, error) {
+	tenantID := reqctx.TenantID(ctx)
+	if tenantID == "" {
+		return r.Repository.GetNoteByID(ctx, id)
+	}
+
+	key := NoteKey{tenantID, id}
+	generation := r.generation.Load()
+
+	// fresh es la nota leída de la base en esta llamada (visible por construcción)
+	var fresh *models.Note
+	note, ok, err := r.notes.GetOrLoad(ctx, key, func(ctx context.Context) (models.Note, bool, error) {
+		n, err := r.Repository.GetNoteByID(ctx, id)
+		if err != nil || n == nil {
+			return models.Note{}, false, err
+		}
+		fresh = n
+		return *n, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case fresh != nil:
+		metrics.CacheRequests.WithLabelValues(noteCacheName, "miss").Inc()
+		if r.generation.Load() != generation {
+			_ = r.notes.Delete(ctx, key)
+		}
+		return fresh, nil
+	case !ok:
+		metrics.CacheRequests.WithLabelValues(noteCacheName, "miss").Inc()
+		return nil, nil
+	case visibleWithoutShare(ctx, note):
+		metrics.CacheRequests.WithLabelValues(noteCacheName, "hit").Inc()
+		return &note, nil
+	default:
+		metrics.CacheRequests.WithLabelValues(noteCacheName, "miss").Inc()
+		return r.Repository.GetNoteByID(ctx, id)
+	}
+}
+
+// GetNotesBatch sirve desde el cache las notas disponibles y pide el resto en un solo query
+func (r *CachedRepository) GetNotesBatch(ctx context.Context, ids []int64) ([]models.Note, error) {
+	tenantID := reqctx.TenantID(ctx)
+	if tenantID == "" || len(ids) == 0 {
+		return r.Repository.GetNotesBatch(ctx, ids)
+	}
+
+	notes := make([]models.Note, 0, len(ids))
+	var missing []int64
+	for _, id := range ids {
+		if note, ok := r.lookup(ctx, NoteKey{tenantID, id}); ok {
+			notes = append(notes, note)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		generation := r.generation.Load()
+		fetched, err := r.Repository.GetNotesBatch(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range fetched {
+			r.store(ctx, generation, tenantID, note)
+		}
+		notes = append(notes, fetched...)
+	}
+
+	// Mismo orden que la consulta (ORDER BY id)
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+	return notes, nil
+}
+
+func (r *CachedRepository) UpdateNote(ctx context.Context, id int64, update models.UpdateNoteRequest) (*models.Note, error) {
+	note, err := r.Repository.UpdateNote(ctx, id, update)
+	r.invalidate(ctx, NoteKey{reqctx.TenantID(ctx), id})
+	return note, err
+}
+
+func (r *CachedRepository) DeleteNote(ctx context.Context, id int64) error {
+	err := r.Repository.DeleteNote(ctx, id)
+	r.invalidate(ctx, NoteKey{reqctx.TenantID(ctx), id})
+	return err
+}
+
+// NoteChanged invalida la nota cambiada en cualquier instancia
+func (r *CachedRepository) NoteChanged(change NoteChange) {
+	r.invalidate(context.Background(), NoteKey{change.TenantID, change.ID})
+}
+
+// ChangesLost vacía el cache: mientras el listener estuvo desconectado pudo perderse cualquier cambio
+func (r *CachedRepository) ChangesLost() {
+	r.generation.Add(1)
+	_ = r.notes.Purge(context.Background())
+}
+
+// lookup retorna la nota cacheada si el usuario del contexto la ve sin compartición
+func (r *CachedRepository) lookup(ctx context.Context, key NoteKey) (models.Note, bool) {
+	note, ok, err := r.notes.Get(ctx, key)
+	if err != nil {
+		metrics.CacheRequests.WithLabelValues(noteCacheName, "error").Inc()
+		return models.Note{}, false
+	}
+	if ok && visibleWithoutShare(ctx, note) {
+		metrics.CacheRequests.WithLabelValues(noteCacheName, "hit").Inc()
+		return note, true
+	}
+	metrics.CacheRequests.WithLabelValues(noteCacheName, "miss").Inc()
+	return models.Note{}, false
+}
+
+func (r *CachedRepository) store(ctx context.Context, generation uint64, tenantID string, note models.Note) {
+	if r.generation.Load() != generation {
+		return
+	}
+	_ = r.notes.Set(ctx, NoteKey{tenantID, note.ID}, note)
+}
+
+// invalidate se aplica también si la escritura falló: el estado de la nota es incierto.
+// Si el cache compartido no responde, la entrada vieja vive hasta su TTL.
+func (r *CachedRepository) invalidate(ctx context.Context, key NoteKey) {
+	r.generation.Add(1)
+	_ = r.notes.Delete(context.WithoutCancel(ctx), key)
+}
+
+func visibleWithoutShare(ctx context.Context, note models.Note) bool {
+	return note.OwnerID == nil || *note.OwnerID == reqctx.UserID(ctx)
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// coalesceTimeout acota la consulta compartida, que no se cancela con el contexto de quien la inició
+const coalesceTimeout = 30 * time.Second
+
+// CoalescingRepository comparte una sola consulta entre llamadas idénticas en curso
+// de GetNoteByID, la primera página de ListNotes y SearchNotes.
+//
+// Las lecturas anónimas del tenant comparten consulta entre todos los clientes; las
+// de un usuario identificado solo con las suyas, porque ve además sus notas y las
+// compartidas con él. Cada escritura cambia la generación de las claves, así una
+// lectura posterior a una escritura nunca se une a una consulta que empezó antes,
+// y las lecturas fijadas a la primaria (read-your-writes) no se unen a las que van
+// a una réplica.
+type CoalescingRepository struct {
+	Repository
+	group      singleflight.Group
+	generation atomic.Uint64
+}
+
+func NewCoalescingRepository(next Repository) *CoalescingRepository {
+	return &CoalescingRepository{Repository: next}
+}
+
+func (r *CoalescingRepository) GetNoteByID(ctx context.Context, id int64) (*models.Note, error) {
+	return coalesce(r, ctx, "GetNoteByID", fmt.Sprintf("note:%d", id), cloneNote,
+		func(ctx context.Context) (*models.Note, error) {
+			return r.Repository.GetNoteByID(ctx, id)
+		})
+}
+
+// ListNotes solo coalesce la primera página: es la que piden todos los clientes a la vez
+func (r *CoalescingRepository) ListNotes(ctx context.Context, params models.PaginationParams) (*models.NotesPage, error) {
+	if !params.CursorTime.IsZero() && params.CursorID != 0 {
+		return r.Repository.ListNotes(ctx, params)
+	}
+
+	return coalesce(r, ctx, "ListNotes", fmt.Sprintf("list:%d", params.Limit), cloneNotesPage,
+		func(ctx context.Context) (*models.NotesPage, error) {
+			return r.Repository.ListNotes(ctx, params)
+		})
+}
+
+func (r *CoalescingRepository) SearchNotes(ctx context.Context, query string, limit int) ([]models.Note, error) {
+	return coalesce(r, ctx, "SearchNotes", fmt.Sprintf("search:%d:%q", limit, query), slices.Clone[[]models.Note],
+		func(ctx context.Context) ([]models.Note, error) {
+			return r.Repository.SearchNotes(ctx, query, limit)
+		})
+}
+
+func (r *CoalescingRepository) CreateNote(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error) {
+	defer r.generation.Add(1)
+	return r.Repository.CreateNote(ctx, note)
+}
+
+func (r *CoalescingRepository) UpdateNote(ctx context.Context, id int64, update models.UpdateNoteRequest) (*models.Note, error) {
+	defer r.generation.Add(1)
+	return r.Repository.UpdateNote(ctx, id, update)
+}
+
+func (r *CoalescingRepository) DeleteNote(ctx context.Context, id int64) error {
+	defer r.generation.Add(1)
+	return r.Repository.DeleteNote(ctx, id)
+}
+
+// primaryReader lo implementa el repositorio que reparte lecturas entre réplicas
+type primaryReader interface {
+	readsFromPrimary(ctx context.Context) bool
+}
+
+// coalesce ejecuta fn una sola vez por clave en curso. Cada llamada espera con su propio
+// contexto y recibe su copia (clone) del resultado compartido.
+func coalesce[T any](r *CoalescingRepository, ctx context.Context, method, key string,
+	clone func(T) T, fn func(context.Context) (T, error)) (T, error) {
+	tenant := reqctx.TenantID(ctx)
+	if tenant == "" {
+		return fn(ctx)
+	}
+
+	// Generación al inicio: una escritura posterior genera una clave nueva
+	key = fmt.Sprintf("%s|%d|%s", tenant, r.generation.Load(), key)
+	if userID := reqctx.UserID(ctx); userID != 0 {
+		key = fmt.Sprintf("user:%d|%s", userID, key)
+	}
+	if p, ok := r.Repository.(primaryReader); ok && p.readsFromPrimary(ctx) {
+		key = "primary|" + key
+	}
+
+	executed := false
+	ch := r.group.DoChan(key, func() (interface{}, error) {
+		executed = true
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), coalesceTimeout)
+		defer cancel()
+		return fn(ctx)
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if executed {
+			metrics.CoalescedCalls.WithLabelValues(method, "executed").Inc()
+		} else {
+			metrics.CoalescedCalls.WithLabelValues(method, "shared").Inc()
+		}
+
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		value := res.Val.(T)
+		if res.Shared {
+			value = clone(value)
+		}
+		return value, nil
+	}
+}
+
+func cloneNote(note *models.Note) *models.Note {
+	if note == nil {
+		return nil
+	}
+	c := *note
+	return &c
+}
+
+func cloneNotesPage(page *models.NotesPage) *models.NotesPage {
+	if page == nil {
+		return nil
+	}
+	c := *page
+	c.Notes = slices.Clone(page.Notes)
+	return &c
+}
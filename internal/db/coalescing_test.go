@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+)
+
+// blockingRepository cuenta las llamadas a GetNoteByID y las retiene hasta release
+type blockingRepository struct {
+	Repository
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingRepository() *blockingRepository {
+	return &blockingRepository{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (r *blockingRepository) GetNoteByID(ctx context.Context, id int64) (*models.Note, error) {
+	r.calls.Add(1)
+	r.started <- struct{}{}
+	<-r.release
+	return &models.Note{ID: id, Title: "nota"}, nil
+}
+
+// getConcurrently lanza una lectura de la nota 1 por contexto y espera que terminen todas
+func getConcurrently(t *testing.T, repo *CoalescingRepository, inner *blockingRepository, ctxs []context.Context) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	for _, ctx := range ctxs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			note, err := repo.GetNoteByID(ctx, 1)
+			if err != nil || note.ID != 1 {
+				t.Errorf("GetNoteByID = %+v, %v", note, err)
+			}
+		}()
+	}
+
+	// Con la primera consulta en curso, dar tiempo a que las demás se unan
+	<-inner.started
+	time.Sleep(50 * time.Millisecond)
+	close(inner.release)
+	wg.Wait()
+}
+
+func TestCoalescingSharesAnonymousReads(t *testing.T) {
+	inner := newBlockingRepository()
+	repo := NewCoalescingRepository(inner)
+
+	// Clientes anónimos distintos (otra IP) del mismo tenant
+	ctxs := make([]context.Context, 20)
+	for i := range ctxs {
+		ctx := reqctx.WithTenantID(context.Background(), "acme")
+		ctxs[i] = reqctx.WithClientIP(ctx, fmt.Sprintf("10.0.0.%d", i+1))
+	}
+
+	getConcurrently(t, repo, inner, ctxs)
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Errorf("llegaron %d consultas al repositorio, se esperaba 1", calls)
+	}
+}
+
+func TestCoalescingSeparatesUsersAndTenants(t *testing.T) {
+	inner := newBlockingRepository()
+	repo := NewCoalescingRepository(inner)
+
+	acme := reqctx.WithTenantID(context.Background(), "acme")
+	globex := reqctx.WithTenantID(context.Background(), "globex")
+	ctxs := []context.Context{
+		acme, acme,
+		reqctx.WithUserID(acme, 1), reqctx.WithUserID(acme, 1),
+		reqctx.WithUserID(acme, 2),
+		globex,
+	}
+
+	getConcurrently(t, repo, inner, ctxs)
+	// acme anónimo, usuario 1, usuario 2 y globex
+	if calls := inner.calls.Load(); calls != 4 {
+		t.Errorf("llegaron %d consultas al repositorio, se esperaban 4", calls)
+	}
+}
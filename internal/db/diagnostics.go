@@ -0,0 +1,262 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrStatementsUnavailable indica que pg_stat_statements no está instalado o habilitado
+var ErrStatementsUnavailable = errors.New("pg_stat_statements no habilitado")
+
+// DiagnosticsRepository expone las vistas de estadísticas de PostgreSQL
+// (antes consultadas a mano con scripts/diagnostic.sql)
+type DiagnosticsRepository interface {
+	IndexUsage(ctx context.Context) ([]models.IndexUsage, error)
+	TableSizes(ctx context.Context) ([]models.TableSize, error)
+	TopStatements(ctx context.Context, orderBy string, limit int) ([]models.StatementStats, error)
+	ResetStatements(ctx context.Context) error
+	Sessions(ctx context.Context, blockedOnly bool) ([]models.Session, error)
+	CacheHitRatio(ctx context.Context) (*models.CacheHitRatio, error)
+}
+
+// Criterios de orden válidos para TopStatements
+var statementOrders = map[string]string{
+	"total": "total_exec_time",
+	"mean":  "mean_exec_time",
+	"max":   "max_exec_time",
+	"calls": "calls",
+	"rows":  "rows",
+}
+
+type Diagnostics struct {
+	pool *pgxpool.Pool
+}
+
+func NewDiagnostics(pool *pgxpool.Pool) *Diagnostics {
+	return &Diagnostics{pool: pool}
+}
+
+// IndexUsage lista los índices con su uso, tamaño y bloat estimado.
+// La estimación (solo btree sobre columnas simples) compara las páginas actuales con
+// las que ocuparían reltuples entradas del ancho medio de pg_stats con fillfactor 90.
+func (d *Diagnostics) IndexUsage(ctx context.Context) ([]models.IndexUsage, error) {
+	query := `
+        SELECT s.schemaname, s.relname, s.indexrelname, pg_get_indexdef(s.indexrelid),
+               i.indisunique, i.indisprimary,
+               s.idx_scan, s.idx_tup_read, s.idx_tup_fetch,
+               pg_relation_size(s.indexrelid),
+               CASE WHEN am.amname = 'btree' AND c.reltuples > 0 AND w.data_width IS NOT NULL THEN
+                   GREATEST(c.relpages - CEIL(c.reltuples * (w.data_width + 12)
+                       / (current_setting('block_size')::numeric * 0.9 - 24)), 0)::bigint
+                   * current_setting('block_size')::bigint
+               END
+        FROM pg_stat_user_indexes s
+        JOIN pg_index i ON i.indexrelid = s.indexrelid
+        JOIN pg_class c ON c.oid = s.indexrelid
+        JOIN pg_am am ON am.oid = c.relam
+        LEFT JOIN LATERAL (
+            SELECT SUM(st.avg_width) AS data_width, COUNT(*) AS cols
+            FROM pg_attribute a
+            JOIN pg_stats st
+              ON st.schemaname = s.schemaname AND st.tablename = s.relname AND st.attname = a.attname
+            WHERE a.attrelid = i.indrelid AND a.attnum = ANY (i.indkey::int2[])
+        ) w ON w.cols = i.indnatts
+        ORDER BY s.schemaname, s.relname, s.indexrelname
+    `
+
+	rows, err := d.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando índices: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := []models.IndexUsage{}
+	for rows.Next() {
+		var idx models.IndexUsage
+		if err := rows.Scan(&idx.Schema, &idx.Table, &idx.Index, &idx.Definition,
+			&idx.IsUnique, &idx.IsPrimary,
+			&idx.Scans, &idx.TuplesRead, &idx.TuplesFetched,
+			&idx.SizeBytes, &idx.EstimatedBloatBytes); err != nil {
+			return nil, fmt.Errorf("error escaneando índice: %w", err)
+		}
+		indexes = append(indexes, idx)
+	}
+
+	return indexes, rows.Err()
+}
+
+// TableSizes lista las tablas por tamaño total con su actividad de scans y vacuum
+func (d *Diagnostics) TableSizes(ctx context.Context) ([]models.TableSize, error) {
+	query := `
+        SELECT t.schemaname, t.relname,
+               pg_total_relation_size(t.relid),
+               pg_relation_size(t.relid),
+               pg_indexes_size(t.relid),
+               COALESCE(pg_total_relation_size(NULLIF(c.reltoastrelid, 0)), 0),
+               t.n_live_tup, t.n_dead_tup,
+               t.seq_scan, t.seq_tup_read, COALESCE(t.idx_scan, 0),
+               t.last_vacuum, t.last_autovacuum, t.last_analyze, t.last_autoanalyze
+        FROM pg_stat_user_tables t
+        JOIN pg_class c ON c.oid = t.relid
+        ORDER BY pg_total_relation_size(t.relid) DESC
+    `
+
+	rows, err := d.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando tablas: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []models.TableSize{}
+	for rows.Next() {
+		var t models.TableSize
+		if err := rows.Scan(&t.Schema, &t.Table,
+			&t.TotalBytes, &t.TableBytes, &t.IndexBytes, &t.ToastBytes,
+			&t.LiveTuples, &t.DeadTuples,
+			&t.SeqScans, &t.SeqTuplesRead, &t.IndexScans,
+			&t.LastVacuum, &t.LastAutovacuum, &t.LastAnalyze, &t.LastAutoanalyze); err != nil {
+			return nil, fmt.Errorf("error escaneando tabla: %w", err)
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, rows.Err()
+}
+
+// TopStatements lista las sentencias de pg_stat_statements ordenadas por orderBy
+// (total, mean, max, calls o rows)
+func (d *Diagnostics) TopStatements(ctx context.Context, orderBy string, limit int) ([]models.StatementStats, error) {
+	column, ok := statementOrders[orderBy]
+	if !ok {
+		return nil, fmt.Errorf("orden inválido %q", orderBy)
+	}
+
+	query := fmt.Sprintf(`
+        SELECT queryid, query, calls,
+               total_exec_time, mean_exec_time, max_exec_time,
+               rows, shared_blks_hit, shared_blks_read
+        FROM pg_stat_statements
+        WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+          AND query NOT LIKE '%%pg_stat_statements%%'
+        ORDER BY %s DESC
+        LIMIT $1
+    `, column)
+
+	rows, err := d.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStatementsUnavailable, err)
+	}
+	defer rows.Close()
+
+	statements := []models.StatementStats{}
+	for rows.Next() {
+		var s models.StatementStats
+		if err := rows.Scan(&s.QueryID, &s.Query, &s.Calls,
+			&s.TotalTimeMs, &s.MeanTimeMs, &s.MaxTimeMs,
+			&s.Rows, &s.SharedBlksHit, &s.SharedBlksRead); err != nil {
+			return nil, fmt.Errorf("error escaneando sentencia: %w", err)
+		}
+		statements = append(statements, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStatementsUnavailable, err)
+	}
+
+	return statements, nil
+}
+
+// ResetStatements reinicia las estadísticas de pg_stat_statements
+func (d *Diagnostics) ResetStatements(ctx context.Context) error {
+	if _, err := d.pool.Exec(ctx, `SELECT pg_stat_statements_reset()`); err != nil {
+		return fmt.Errorf("%w: %v", ErrStatementsUnavailable, err)
+	}
+	return nil
+}
+
+// Sessions lista las conexiones de clientes a la base actual con los PIDs que las bloquean
+func (d *Diagnostics) Sessions(ctx context.Context, blockedOnly bool) ([]models.Session, error) {
+	query := `
+        SELECT pid, COALESCE(usename, ''), application_name,
+               COALESCE(host(client_addr), ''), COALESCE(state, ''),
+               COALESCE(wait_event_type, ''), COALESCE(wait_event, ''),
+               backend_start, query_start,
+               CASE WHEN state = 'active' THEN EXTRACT(EPOCH FROM now() - query_start) * 1000 END,
+               pg_blocking_pids(pid), LEFT(query, 1000)
+        FROM pg_stat_activity
+        WHERE datname = current_database()
+          AND backend_type = 'client backend'
+          AND pid <> pg_backend_pid()
+          AND ($1 = false OR cardinality(pg_blocking_pids(pid)) > 0)
+        ORDER BY query_start NULLS LAST
+    `
+
+	rows, err := d.pool.Query(ctx, query, blockedOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando sesiones: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.PID, &s.User, &s.ApplicationName,
+			&s.ClientAddr, &s.State,
+			&s.WaitEventType, &s.WaitEvent,
+			&s.BackendStart, &s.QueryStart,
+			&s.QueryDurationMs,
+			&s.BlockedBy, &s.Query); err != nil {
+			return nil, fmt.Errorf("error escaneando sesión: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// CacheHitRatio calcula la proporción de bloques leídos desde shared_buffers,
+// para la base completa y por tabla (heap e índices)
+func (d *Diagnostics) CacheHitRatio(ctx context.Context) (*models.CacheHitRatio, error) {
+	var ratio models.CacheHitRatio
+
+	err := d.pool.QueryRow(ctx, `
+        SELECT COALESCE(SUM(blks_hit)::float8 / NULLIF(SUM(blks_hit) + SUM(blks_read), 0), 0)
+        FROM pg_stat_database
+        WHERE datname = current_database()
+    `).Scan(&ratio.Database)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando cache de la base: %w", err)
+	}
+
+	rows, err := d.pool.Query(ctx, `
+        SELECT relname,
+               heap_blks_hit::float8 / NULLIF(heap_blks_hit + heap_blks_read, 0),
+               idx_blks_hit::float8 / NULLIF(idx_blks_hit + idx_blks_read, 0)
+        FROM pg_statio_user_tables
+        ORDER BY relname
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando cache por tabla: %w", err)
+	}
+	defer rows.Close()
+
+	ratio.Tables = []models.TableCacheRatio{}
+	for rows.Next() {
+		var t models.TableCacheRatio
+		if err := rows.Scan(&t.Table, &t.HeapRatio, &t.IndexRatio); err != nil {
+			return nil, fmt.Errorf("error escaneando cache de tabla: %w", err)
+		}
+		ratio.Tables = append(ratio.Tables, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &ratio, nil
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInvalidExplain indica una consulta desconocida o sin los parámetros que necesita
+var ErrInvalidExplain = errors.New("consulta a analizar inválida")
+
+// errExplainRollback fuerza el rollback de withTenant: EXPLAIN ANALYZE ejecuta la sentencia
+var errExplainRollback = errors.New("rollback de explain")
+
+// errExplainEmpty indica que EXPLAIN devolvió un JSON válido pero sin planes
+var errExplainEmpty = errors.New("EXPLAIN sin resultados")
+
+// ExplainRepository analiza con EXPLAIN ANALYZE las consultas de lectura del repositorio
+type ExplainRepository interface {
+	ExplainQuery(ctx context.Context, req models.ExplainRequest) (*models.ExplainResult, error)
+}
+
+// explainNode refleja los campos usados de un nodo de EXPLAIN (FORMAT JSON)
+type explainNode struct {
+	NodeType         string        `json:"Node Type"`
+	RelationName     string        `json:"Relation Name"`
+	IndexName        string        `json:"Index Name"`
+	PlanRows         float64       `json:"Plan Rows"`
+	ActualRows       float64       `json:"Actual Rows"`
+	ActualLoops      float64       `json:"Actual Loops"`
+	ActualTotalTime  float64       `json:"Actual Total Time"`
+	SharedHitBlocks  int64         `json:"Shared Hit Blocks"`
+	SharedReadBlocks int64         `json:"Shared Read Blocks"`
+	Plans            []explainNode `json:"Plans"`
+}
+
+type explainOutput struct {
+	Plan          explainNode `json:"Plan"`
+	PlanningTime  float64     `json:"Planning Time"`
+	ExecutionTime float64     `json:"Execution Time"`
+}
+
+// explainStatement devuelve el SQL y los argumentos que usaría el repositorio para la consulta
+func explainStatement(req models.ExplainRequest) (string, []interface{}, error) {
+	limit := req.Limit
+	if limit == 0 {
+		limit = 20
+	}
+
+	switch req.Query {
+	case models.ExplainListNotes:
+		return listNotesFirstPageQuery, []interface{}{limit + 1, req.UserID}, nil
+	case models.ExplainListNotesNext:
+		if req.CursorTime.IsZero() || req.CursorID == 0 {
+			return "", nil, fmt.Errorf("%w: %s requiere cursor_time y cursor_id", ErrInvalidExplain, req.Query)
+		}
+		return listNotesNextPageQuery, []interface{}{req.CursorTime, req.CursorID, limit + 1, req.UserID}, nil
+	case models.ExplainSearchNotes:
+		if req.Search == "" {
+			return "", nil, fmt.Errorf("%w: %s requiere search", ErrInvalidExplain, req.Query)
+		}
+		return searchNotesQuery, []interface{}{req.Search, limit, req.UserID}, nil
+	case models.ExplainNotesBatch:
+		if len(req.IDs) == 0 {
+			return "", nil, fmt.Errorf("%w: %s requiere ids", ErrInvalidExplain, req.Query)
+		}
+		return getNotesBatchQuery, []interface{}{req.IDs, req.UserID}, nil
+	default:
+		return "", nil, fmt.Errorf("%w: consulta desconocida %q", ErrInvalidExplain, req.Query)
+	}
+}
+
+// ExplainQuery ejecuta EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) sobre la consulta pedida con el
+// tenant del contexto, dentro de una transacción que siempre se descarta
+func (r *PostgresRepository) ExplainQuery(ctx context.Context, req models.ExplainRequest) (*models.ExplainResult, error) {
+	sql, args, err := explainStatement(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// explainKey evita que el propio análisis termine en slow_query_plans
+	ctx = context.WithValue(ctx, explainKey{}, true)
+
+	var raw []byte
+	err = r.withTenant(ctx, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "+sql, args...).Scan(&raw); err != nil {
+			return fmt.Errorf("error ejecutando EXPLAIN: %w", err)
+		}
+		return errExplainRollback
+	})
+	if err != nil && !errors.Is(err, errExplainRollback) {
+		return nil, err
+	}
+
+	output, err := parseExplain(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ExplainResult{
+		Query:           req.Query,
+		SQL:             compactSQL(sql),
+		PlanningTimeMs:  output.PlanningTime,
+		ExecutionTimeMs: output.ExecutionTime,
+		IndexesUsed:     []string{},
+		SeqScans:        []string{},
+		Nodes:           []models.PlanNode{},
+	}
+	summarizePlan(result, output.Plan, 0)
+
+	if req.Raw {
+		result.Plan = json.RawMessage(raw)
+	}
+
+	return result, nil
+}
+
+// parseExplain decodifica la salida de EXPLAIN (FORMAT JSON), un arreglo con un plan por sentencia
+func parseExplain(raw []byte) (explainOutput, error) {
+	var outputs []explainOutput
+	if err := json.Unmarshal(raw, &outputs); err != nil {
+		return explainOutput{}, fmt.Errorf("error interpretando plan: %w", err)
+	}
+	if len(outputs) == 0 {
+		return explainOutput{}, errExplainEmpty
+	}
+	return outputs[0], nil
+}
+
+// summarizePlan aplana el árbol en preorden y anota índices y seq scans
+func summarizePlan(result *models.ExplainResult, node explainNode, depth int) {
+	result.Nodes = append(result.Nodes, models.PlanNode{
+		Depth:             depth,
+		NodeType:          node.NodeType,
+		Relation:          node.RelationName,
+		Index:             node.IndexName,
+		PlanRows:          node.PlanRows,
+		ActualRows:        node.ActualRows,
+		Loops:             node.ActualLoops,
+		ActualTotalTimeMs: node.ActualTotalTime,
+		SharedHitBlocks:   node.SharedHitBlocks,
+		SharedReadBlocks:  node.SharedReadBlocks,
+	})
+
+	if node.IndexName != "" && !slices.Contains(result.IndexesUsed, node.IndexName) {
+		result.IndexesUsed = append(result.IndexesUsed, node.IndexName)
+	}
+	if node.NodeType == "Seq Scan" && !slices.Contains(result.SeqScans, node.RelationName) {
+		result.SeqScans = append(result.SeqScans, node.RelationName)
+	}
+
+	for _, child := range node.Plans {
+		summarizePlan(result, child, depth+1)
+	}
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+)
+
+func TestParseExplain(t *testing.T) {
+	output, err := parseExplain([]byte(`[{"Plan": {"Node Type": "Index Scan", "Index Name": "idx_notes_created_id"},
+		"Planning Time": 0.1, "Execution Time": 0.5}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Plan.IndexName != "idx_notes_created_id" || output.ExecutionTime != 0.5 {
+		t.Errorf("plan = %+v", output)
+	}
+
+	// Sin filas el error no puede quedar vacío
+	for _, raw := range []string{`[]`, `null`} {
+		if _, err := parseExplain([]byte(raw)); !errors.Is(err, errExplainEmpty) {
+			t.Errorf("parseExplain(%s) = %v, se esperaba errExplainEmpty", raw, err)
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if _, err := parseExplain([]byte(`[{"Plan":`)); !errors.As(err, &syntaxErr) {
+		t.Errorf("JSON inválido = %v, se esperaba el error de decodificación", err)
+	}
+}
+
+func TestSummarizePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		nodes   []string // "profundidad tipo relación índice" en preorden
+		indexes []string
+		seqScan []string
+	}{
+		{
+			name: "página por keyset",
+			plan: `{"Node Type": "Limit", "Plans": [
+				{"Node Type": "Index Scan", "Relation Name": "notes", "Index Name": "idx_notes_created_id"}]}`,
+			nodes:   []string{"0 Limit  ", "1 Index Scan notes idx_notes_created_id"},
+			indexes: []string{"idx_notes_created_id"},
+			seqScan: []string{},
+		},
+		{
+			name: "notas compartidas anidadas",
+			plan: `{"Node Type": "Limit", "Plans": [
+				{"Node Type": "Nested Loop", "Plans": [
+					{"Node Type": "Seq Scan", "Relation Name": "note_shares"},
+					{"Node Type": "Index Scan", "Relation Name": "notes", "Index Name": "notes_pkey"}]},
+				{"Node Type": "Bitmap Heap Scan", "Relation Name": "notes", "Plans": [
+					{"Node Type": "Bitmap Index Scan", "Index Name": "idx_notes_title_trgm"}]}]}`,
+			nodes: []string{
+				"0 Limit  ",
+				"1 Nested Loop  ",
+				"2 Seq Scan note_shares ",
+				"2 Index Scan notes notes_pkey",
+				"1 Bitmap Heap Scan notes ",
+				"2 Bitmap Index Scan  idx_notes_title_trgm",
+			},
+			indexes: []string{"notes_pkey", "idx_notes_title_trgm"},
+			seqScan: []string{"note_shares"},
+		},
+		{
+			name: "índices y seq scans repetidos se listan una vez",
+			plan: `{"Node Type": "Append", "Plans": [
+				{"Node Type": "Seq Scan", "Relation Name": "notes"},
+				{"Node Type": "Index Only Scan", "Relation Name": "notes", "Index Name": "notes_pkey"},
+				{"Node Type": "Subquery Scan", "Plans": [
+					{"Node Type": "Seq Scan", "Relation Name": "notes"},
+					{"Node Type": "Index Only Scan", "Relation Name": "notes", "Index Name": "notes_pkey"}]}]}`,
+			nodes: []string{
+				"0 Append  ",
+				"1 Seq Scan notes ",
+				"1 Index Only Scan notes notes_pkey",
+				"1 Subquery Scan  ",
+				"2 Seq Scan notes ",
+				"2 Index Only Scan notes notes_pkey",
+			},
+			indexes: []string{"notes_pkey"},
+			seqScan: []string{"notes"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root explainNode
+			if err := json.Unmarshal([]byte(tt.plan), &root); err != nil {
+				t.Fatal(err)
+			}
+			result := &models.ExplainResult{IndexesUsed: []string{}, SeqScans: []string{}}
+			summarizePlan(result, root, 0)
+
+			nodes := make([]string, len(result.Nodes))
+			for i, n := range result.Nodes {
+				nodes[i] = fmt.Sprintf("%d %s %s %s", n.Depth, n.NodeType, n.Relation, n.Index)
+			}
+			if !slices.Equal(nodes, tt.nodes) {
+				t.Errorf("nodos:\n%s\nse esperaba:\n%s", strings.Join(nodes, "\n"), strings.Join(tt.nodes, "\n"))
+			}
+			if !slices.Equal(result.IndexesUsed, tt.indexes) {
+				t.Errorf("índices = %v, se esperaba %v", result.IndexesUsed, tt.indexes)
+			}
+			if !slices.Equal(result.SeqScans, tt.seqScan) {
+				t.Errorf("seq scans = %v, se esperaba %v", result.SeqScans, tt.seqScan)
+			}
+		})
+	}
+}
+
+// Las métricas de cada nodo pasan tal cual, también las de los nodos anidados
+func TestSummarizePlanNodeMetrics(t *testing.T) {
+	output, err := parseExplain([]byte(`[{"Plan": {"Node Type": "Limit", "Actual Total Time": 1.5, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "notes", "Plan Rows": 100, "Actual Rows": 20,
+		 "Actual Loops": 3, "Actual Total Time": 1.2, "Shared Hit Blocks": 40, "Shared Read Blocks": 7}]}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := &models.ExplainResult{IndexesUsed: []string{}, SeqScans: []string{}}
+	summarizePlan(result, output.Plan, 0)
+
+	want := models.PlanNode{Depth: 1, NodeType: "Seq Scan", Relation: "notes", PlanRows: 100, ActualRows: 20,
+		Loops: 3, ActualTotalTimeMs: 1.2, SharedHitBlocks: 40, SharedReadBlocks: 7}
+	if len(result.Nodes) != 2 || result.Nodes[1] != want {
+		t.Errorf("nodos = %+v\nse esperaba el segundo = %+v", result.Nodes, want)
+	}
+	if result.Nodes[0].ActualTotalTimeMs != 1.5 {
+		t.Errorf("raíz = %+v", result.Nodes[0])
+	}
+}
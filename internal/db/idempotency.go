@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdempotencyStore guarda en Postgres las respuestas asociadas a cada Idempotency-Key.
+// Las claves se aíslan por tenant (RLS) y por usuario.
+type IdempotencyStore struct {
+	repo *PostgresRepository
+}
+
+func NewIdempotencyStore(pool *pgxpool.Pool) *IdempotencyStore {
+	return &IdempotencyStore{repo: NewPostgresRepository(pool)}
+}
+
+// Claim reserva la clave para esta petición durante lease. Si ya existe una reserva
+// vigente la retorna con claimed = false; se reutilizan las claves vencidas y las
+// reservas en curso cuyo lease venció (el proceso que las tomó no las liberó).
+func (s *IdempotencyStore) Claim(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (*models.IdempotencyRecord, bool, error) {
+	claimQuery := `
+        INSERT INTO idempotency_keys (user_id, key, request_hash, status, expires_at, locked_until)
+        VALUES ($1, $2, $3, 'in_progress', NOW() + make_interval(secs => $4), NOW() + make_interval(secs => $5))
+        ON CONFLICT (tenant_id, user_id, key) DO UPDATE
+        SET request_hash = EXCLUDED.request_hash,
+            status = 'in_progress',
+            response_status = NULL,
+            content_type = NULL,
+            response_body = NULL,
+            created_at = NOW(),
+            expires_at = EXCLUDED.expires_at,
+            locked_until = EXCLUDED.locked_until
+        WHERE idempotency_keys.expires_at <= NOW()
+           OR (idempotency_keys.status = 'in_progress' AND idempotency_keys.locked_until <= NOW())
+        RETURNING key
+    `
+
+	existingQuery := `
+        SELECT key, request_hash, status, COALESCE(response_status, 0),
+               COALESCE(content_type, ''), COALESCE(response_body, ''::bytea)
+        FROM idempotency_keys
+        WHERE user_id = $1 AND key = $2
+    `
+
+	userID := reqctx.UserID(ctx)
+	var record models.IdempotencyRecord
+	claimed := false
+
+	err := s.repo.withTenant(ctx, func(tx pgx.Tx) error {
+		var claimedKey string
+		err := tx.QueryRow(ctx, claimQuery, userID, key, requestHash, ttl.Seconds(), lease.Seconds()).Scan(&claimedKey)
+		if err == nil {
+			claimed = true
+			record = models.IdempotencyRecord{Key: key, RequestHash: requestHash, Status: models.IdempotencyInProgress}
+			return nil
+		}
+		if err != pgx.ErrNoRows {
+			return err
+		}
+
+		// Conflicto con una reserva vigente: retornar su estado
+		return tx.QueryRow(ctx, existingQuery, userID, key).
+			Scan(&record.Key, &record.RequestHash, &record.Status, &record.ResponseStatus,
+				&record.ContentType, &record.ResponseBody)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("error reservando clave de idempotencia: %w", err)
+	}
+
+	return &record, claimed, nil
+}
+
+// Complete guarda la respuesta final de la petición que reservó la clave
+func (s *IdempotencyStore) Complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	query := `
+        UPDATE idempotency_keys
+        SET status = 'completed', response_status = $3, content_type = $4, response_body = $5
+        WHERE user_id = $1 AND key = $2
+    `
+
+	err := s.repo.withTenant(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query, reqctx.UserID(ctx), key, status, contentType, body)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error guardando respuesta idempotente: %w", err)
+	}
+
+	return nil
+}
+
+// Release libera una reserva en curso (p. ej. tras un 5xx) para que el cliente pueda reintentar
+func (s *IdempotencyStore) Release(ctx context.Context, key string) error {
+	query := `
+        DELETE FROM idempotency_keys
+        WHERE user_id = $1 AND key = $2 AND status = 'in_progress'
+    `
+
+	err := s.repo.withTenant(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, query, reqctx.UserID(ctx), key)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error liberando clave de idempotencia: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeExpired elimina las claves vencidas de todos los tenants
+func (s *IdempotencyStore) PurgeExpired(ctx context.Context) (int64, error) {
+	var deleted int64
+	if err := s.repo.pool.QueryRow(ctx, `SELECT purge_expired_idempotency_keys()`).Scan(&deleted); err != nil {
+		return 0, fmt.Errorf("error purgando claves de idempotencia: %w", err)
+	}
+	return deleted, nil
+}
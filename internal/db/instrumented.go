@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+)
+
+// InstrumentedRepository mide la latencia de cada método del Repository envuelto
+type InstrumentedRepository struct {
+	next Repository
+}
+
+func NewInstrumentedRepository(next Repository) *InstrumentedRepository {
+	return &InstrumentedRepository{next: next}
+}
+
+func observe(method string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.RepositoryDuration.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (r *InstrumentedRepository) CreateNote(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error) {
+	start := time.Now()
+	result, err := r.next.CreateNote(ctx, note)
+	observe("CreateNote", start, err)
+	return result, err
+}
+
+func (r *InstrumentedRepository) GetNoteByID(ctx context.Context, id int64) (*models.Note, error) {
+	start := time.Now()
+	result, err := r.next.GetNoteByID(ctx, id)
+	observe("GetNoteByID", start, err)
+	return result, err
+}
+
+func (r *InstrumentedRepository) GetNotesBatch(ctx context.Context, ids []int64) ([]models.Note, error) {
+	start := time.Now()
+	result, err := r.next.GetNotesBatch(ctx, ids)
+	observe("GetNotesBatch", start, err)
+	return result, err
+}
+
+func (r *InstrumentedRepository) ListNotes(ctx context.Context, params models.PaginationParams) (*models.NotesPage, error) {
+	start := time.Now()
+	result, err := r.next.ListNotes(ctx, params)
+	observe("ListNotes", start, err)
+	return result, err
+}
+
+func (r *InstrumentedRepository) SearchNotes(ctx context.Context, query string, limit int) ([]models.Note, error) {
+	start := time.Now()
+	result, err := r.next.SearchNotes(ctx, query, limit)
+	observe("SearchNotes", start, err)
+	return result, err
+}
+
+func (r *InstrumentedRepository) UpdateNote(ctx context.Context, id int64, update models.UpdateNoteRequest) (*models.Note, error) {
+	start := time.Now()
+	result, err := r.next.UpdateNote(ctx, id, update)
+	observe("UpdateNote", start, err)
+	return result, err
+}
+
+func (r *InstrumentedRepository) DeleteNote(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := r.next.DeleteNote(ctx, id)
+	observe("DeleteNote", start, err)
+	return err
+}
+
+func (r *InstrumentedRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	start := time.Now()
+	result, err := r.next.GetStats(ctx)
+	observe("GetStats", start, err)
+	return result, err
+}
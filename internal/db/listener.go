@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// noteChangesChannel es el canal que notifica el trigger notes_notify_change (migración 0002)
+const noteChangesChannel = "note_changes"
+
+const (
+	listenerMinBackoff = 500 * time.Millisecond
+	listenerMaxBackoff = 30 * time.Second
+	// listenerPingInterval despierta la espera para detectar conexiones caídas sin tráfico
+	listenerPingInterval = 30 * time.Second
+)
+
+// NoteChange es un cambio de una nota notificado por la base
+type NoteChange struct {
+	TenantID string `json:"tenant_id"`
+	ID       int64  `json:"id"`
+	Op       string `json:"op"` // insert, update o delete
+}
+
+// NoteChangeHandler recibe los cambios que escucha NoteListener
+type NoteChangeHandler interface {
+	// NoteChanged se llama por cada notificación recibida
+	NoteChanged(change NoteChange)
+	// ChangesLost se llama al (re)conectar: las notificaciones emitidas sin conexión se perdieron
+	ChangesLost()
+}
+
+// NoteListener escucha note_changes en una conexión dedicada (fuera del pool) y
+// reparte los cambios a los handlers. Si la conexión se cae reconecta con backoff
+// exponencial. Así una escritura en cualquier instancia invalida los caches de todas.
+type NoteListener struct {
+	connString string
+	logger     *slog.Logger
+	handlers   []NoteChangeHandler
+}
+
+func NewNoteListener(connString string, logger *slog.Logger, handlers ...NoteChangeHandler) *NoteListener {
+	return &NoteListener{connString: connString, logger: logger, handlers: handlers}
+}
+
+// Start escucha en segundo plano hasta que ctx se cancele
+func (l *NoteListener) Start(ctx context.Context) {
+	go l.run(ctx)
+}
+
+func (l *NoteListener) run(ctx context.Context) {
+	backoff := listenerMinBackoff
+	for {
+		err := l.listen(ctx, func() { backoff = listenerMinBackoff })
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Jitter para que las instancias no reconecten todas a la vez
+		wait := backoff/2 + rand.N(backoff/2+1)
+		l.logger.Warn("listener de notas desconectado", "error", err, "retry_in", wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, listenerMaxBackoff)
+	}
+}
+
+// listen conecta, ejecuta LISTEN y reparte notificaciones hasta que la conexión falle.
+// connected se llama una vez establecida la escucha.
+func (l *NoteListener) listen(ctx context.Context, connected func()) error {
+	conn, err := pgx.Connect(ctx, l.connString)
+	if err != nil {
+		return fmt.Errorf("error conectando: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+noteChangesChannel); err != nil {
+		return fmt.Errorf("error en LISTEN: %w", err)
+	}
+
+	connected()
+	l.logger.Info("listener de notas conectado", "channel", noteChangesChannel)
+	for _, h := range l.handlers {
+		h.ChangesLost()
+	}
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, listenerPingInterval)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Un timeout no cierra la conexión: se verifica que siga viva y se vuelve a esperar
+			if pgconn.Timeout(err) {
+				if err := l.ping(ctx, conn); err != nil {
+					return fmt.Errorf("error en ping: %w", err)
+				}
+				continue
+			}
+			return fmt.Errorf("error esperando notificación: %w", err)
+		}
+
+		var change NoteChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			l.logger.Warn("notificación de nota inválida", "payload", notification.Payload, "error", err)
+			continue
+		}
+
+		for _, h := range l.handlers {
+			h.NoteChanged(change)
+		}
+	}
+}
+
+func (l *NoteListener) ping(ctx context.Context, conn *pgx.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return conn.Ping(ctx)
+}
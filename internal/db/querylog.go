@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryLogger registra cada sentencia SQL en nivel debug con la duración y el
+// request_id del contexto. No registra argumentos para no filtrar datos de usuario.
+type queryLogger struct {
+	logger *slog.Logger
+}
+
+type queryLogKey struct{}
+
+type queryLogStart struct {
+	sql   string
+	start time.Time
+}
+
+func (t *queryLogger) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !t.logger.Enabled(ctx, slog.LevelDebug) {
+		return ctx
+	}
+	return context.WithValue(ctx, queryLogKey{}, queryLogStart{sql: data.SQL, start: time.Now()})
+}
+
+func (t *queryLogger) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryLogKey{}).(queryLogStart)
+	if !ok {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("sql", compactSQL(start.sql)),
+		slog.Duration("duration", time.Since(start.start)),
+		slog.Int64("rows", data.CommandTag.RowsAffected()),
+	}
+
+	if data.Err != nil {
+		attrs = append(attrs, slog.String("error", data.Err.Error()))
+		t.logger.LogAttrs(ctx, slog.LevelDebug, "consulta SQL fallida", attrs...)
+		return
+	}
+
+	t.logger.LogAttrs(ctx, slog.LevelDebug, "consulta SQL", attrs...)
+}
+
+// compactSQL colapsa espacios y saltos de línea para que la consulta quepa en una línea de log
+func compactSQL(sql string) string {
+	out := make([]byte, 0, len(sql))
+	space := false
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		if ch == ' ' || ch == '\n' || ch == '\t' || ch == '\r' {
+			space = len(out) > 0
+			continue
+		}
+		if space {
+			out = append(out, ' ')
+			space = false
+		}
+		out = append(out, ch)
+	}
+	return string(out)
+}
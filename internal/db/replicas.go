@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReplicaOptions ajusta el router de réplicas
+type ReplicaOptions struct {
+	// CheckInterval es la frecuencia del health check de las réplicas
+	CheckInterval time.Duration
+	// MaxLag expulsa las réplicas con más retraso de replicación (0 no lo verifica).
+	// El retraso se mide con pg_last_xact_replay_timestamp(), que también crece si la
+	// primaria no recibe escrituras.
+	MaxLag time.Duration
+	// ReadYourWrites fija un cliente a la primaria durante este tiempo después de
+	// escribir, para que vea sus propios cambios aunque la réplica vaya atrasada (0 lo deshabilita)
+	ReadYourWrites time.Duration
+}
+
+// ReplicaRouter reparte las lecturas entre réplicas sanas con round-robin.
+// Si no hay réplicas sanas, o el cliente escribió hace poco, la lectura va a la primaria.
+// Las fijaciones de read-your-writes viven en memoria: con varias instancias de la API
+// solo aplican a la instancia que recibió la escritura.
+type ReplicaRouter struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	opts     ReplicaOptions
+	logger   *slog.Logger
+	next     atomic.Uint64
+
+	mu   sync.Mutex
+	pins map[string]time.Time
+}
+
+type replica struct {
+	name    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+	lag     atomic.Int64 // nanosegundos
+}
+
+// ReplicaStatus es el estado de una réplica (para /stats)
+type ReplicaStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	LagMs   int64  `json:"lag_ms"`
+}
+
+// NewReplicaRouter crea el router; las réplicas empiezan sanas (Open ya verificó la conexión)
+func NewReplicaRouter(primary *pgxpool.Pool, replicas []*Database, opts ReplicaOptions, logger *slog.Logger) *ReplicaRouter {
+	router := &ReplicaRouter{
+		primary: primary,
+		opts:    opts,
+		logger:  logger,
+		pins:    make(map[string]time.Time),
+	}
+
+	for _, d := range replicas {
+		r := &replica{name: d.Name(), pool: d.Pool()}
+		r.healthy.Store(true)
+		metrics.ReplicaHealthy.WithLabelValues(r.name).Set(1)
+		router.replicas = append(router.replicas, r)
+	}
+
+	return router
+}
+
+// Start ejecuta el health check periódico hasta que ctx se cancele
+func (rr *ReplicaRouter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(rr.opts.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rr.checkReplicas(ctx)
+				rr.purgePins()
+			}
+		}
+	}()
+}
+
+// ReadPool retorna el pool para una lectura que tolera réplicas
+func (rr *ReplicaRouter) ReadPool(ctx context.Context) *pgxpool.Pool {
+	if rr.pinned(ctx) {
+		metrics.DBReads.WithLabelValues("primary", "read_your_writes").Inc()
+		return rr.primary
+	}
+
+	n := len(rr.replicas)
+	start := rr.next.Add(1)
+	for i := 0; i < n; i++ {
+		r := rr.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			metrics.DBReads.WithLabelValues(r.name, "replica").Inc()
+			return r.pool
+		}
+	}
+
+	metrics.DBReads.WithLabelValues("primary", "no_healthy_replica").Inc()
+	return rr.primary
+}
+
+// MarkWrite fija al cliente del contexto a la primaria durante ReadYourWrites
+func (rr *ReplicaRouter) MarkWrite(ctx context.Context) {
+	key := pinKey(ctx)
+	if rr.opts.ReadYourWrites <= 0 || key == "" {
+		return
+	}
+
+	rr.mu.Lock()
+	rr.pins[key] = time.Now().Add(rr.opts.ReadYourWrites)
+	rr.mu.Unlock()
+}
+
+// Status retorna el estado actual de las réplicas
+func (rr *ReplicaRouter) Status() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(rr.replicas))
+	for _, r := range rr.replicas {
+		statuses = append(statuses, ReplicaStatus{
+			Name:    r.name,
+			Healthy: r.healthy.Load(),
+			LagMs:   time.Duration(r.lag.Load()).Milliseconds(),
+		})
+	}
+	return statuses
+}
+
+func (rr *ReplicaRouter) pinned(ctx context.Context) bool {
+	key := pinKey(ctx)
+	if rr.opts.ReadYourWrites <= 0 || key == "" {
+		return false
+	}
+
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	until, ok := rr.pins[key]
+	return ok && time.Now().Before(until)
+}
+
+func (rr *ReplicaRouter) purgePins() {
+	now := time.Now()
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	for key, until := range rr.pins {
+		if now.After(until) {
+			delete(rr.pins, key)
+		}
+	}
+}
+
+func (rr *ReplicaRouter) checkReplicas(ctx context.Context) {
+	for _, r := range rr.replicas {
+		err := rr.check(ctx, r)
+		healthy := err == nil
+
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				rr.logger.Info("réplica reincorporada", "replica", r.name)
+			} else {
+				rr.logger.Warn("réplica expulsada", "replica", r.name, "error", err)
+			}
+		}
+
+		value := 0.0
+		if healthy {
+			value = 1
+		}
+		metrics.ReplicaHealthy.WithLabelValues(r.name).Set(value)
+	}
+}
+
+// check verifica que la réplica responda y, si MaxLag > 0, que su retraso sea aceptable
+func (rr *ReplicaRouter) check(ctx context.Context, r *replica) error {
+	ctx, cancel := context.WithTimeout(ctx, rr.opts.CheckInterval)
+	defer cancel()
+
+	var lagSeconds float64
+	err := r.pool.QueryRow(ctx, `
+        SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)::float8
+    `).Scan(&lagSeconds)
+	if err != nil {
+		return err
+	}
+
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	r.lag.Store(int64(lag))
+
+	if rr.opts.MaxLag > 0 && lag > rr.opts.MaxLag {
+		return fmt.Errorf("retraso de replicación %s supera %s", lag.Round(time.Millisecond), rr.opts.MaxLag)
+	}
+
+	return nil
+}
+
+// pinKey identifica al cliente para read-your-writes: el usuario si está
+// identificado, si no la IP, siempre dentro del tenant
+func pinKey(ctx context.Context) string {
+	tenant := reqctx.TenantID(ctx)
+	if userID := reqctx.UserID(ctx); userID != 0 {
+		return fmt.Sprintf("%s|user:%d", tenant, userID)
+	}
+	if ip := reqctx.ClientIP(ctx); ip != "" {
+		return tenant + "|ip:" + ip
+	}
+	return ""
+}
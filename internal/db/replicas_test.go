@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lazyPool crea un pool sin conectar: el router solo compara punteros
+func lazyPool(t *testing.T, name string) *pgxpool.Pool {
+	t.Helper()
+	pool, err := pgxpool.New(context.Background(), "postgres://notes@127.0.0.1:1/"+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func newTestRouter(t *testing.T, opts ReplicaOptions, replicas ...string) (*ReplicaRouter, *pgxpool.Pool) {
+	t.Helper()
+	primary := lazyPool(t, "primary")
+	var dbs []*Database
+	for _, name := range replicas {
+		dbs = append(dbs, &Database{name: name, pool: lazyPool(t, name)})
+	}
+	return NewReplicaRouter(primary, dbs, opts, slog.New(slog.DiscardHandler)), primary
+}
+
+func TestReplicaRouterRoundRobin(t *testing.T) {
+	router, primary := newTestRouter(t, ReplicaOptions{}, "r1", "r2")
+	ctx := context.Background()
+
+	seen := make(map[*pgxpool.Pool]int)
+	for range 10 {
+		seen[router.ReadPool(ctx)]++
+	}
+	if seen[primary] != 0 || seen[router.replicas[0].pool] != 5 || seen[router.replicas[1].pool] != 5 {
+		t.Errorf("reparto = primaria %d, r1 %d, r2 %d; se esperaba 0, 5, 5",
+			seen[primary], seen[router.replicas[0].pool], seen[router.replicas[1].pool])
+	}
+
+	// Una réplica expulsada no recibe lecturas
+	router.replicas[0].healthy.Store(false)
+	for range 4 {
+		if pool := router.ReadPool(ctx); pool != router.replicas[1].pool {
+			t.Fatal("la lectura fue a una réplica expulsada o a la primaria")
+		}
+	}
+}
+
+func TestReplicaRouterPrimaryFallback(t *testing.T) {
+	router, primary := newTestRouter(t, ReplicaOptions{}, "r1", "r2")
+	for _, r := range router.replicas {
+		r.healthy.Store(false)
+	}
+	if router.ReadPool(context.Background()) != primary {
+		t.Error("sin réplicas sanas la lectura debe ir a la primaria")
+	}
+
+	// Sin réplicas configuradas
+	router, primary = newTestRouter(t, ReplicaOptions{})
+	if router.ReadPool(context.Background()) != primary {
+		t.Error("sin réplicas la lectura debe ir a la primaria")
+	}
+}
+
+func TestReplicaRouterReadYourWrites(t *testing.T) {
+	router, primary := newTestRouter(t, ReplicaOptions{ReadYourWrites: time.Minute}, "r1")
+
+	tenant := reqctx.WithTenantID(context.Background(), "acme")
+	user := reqctx.WithUserID(tenant, 1)
+	router.MarkWrite(user)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		primary bool
+	}{
+		{"quien escribió", reqctx.WithClientIP(user, "10.0.0.9"), true},
+		{"otro usuario", reqctx.WithUserID(tenant, 2), false},
+		{"mismo usuario en otro tenant", reqctx.WithUserID(reqctx.WithTenantID(context.Background(), "otro"), 1), false},
+		{"anónimo", tenant, false},
+	}
+	for _, tt := range tests {
+		if got := router.ReadPool(tt.ctx) == primary; got != tt.primary {
+			t.Errorf("%s: lectura en la primaria = %v, se esperaba %v", tt.name, got, tt.primary)
+		}
+	}
+
+	// Los anónimos se fijan por IP; sin usuario ni IP no hay a quién fijar
+	anonymous := reqctx.WithClientIP(tenant, "10.0.0.1")
+	router.MarkWrite(anonymous)
+	if router.ReadPool(anonymous) != primary {
+		t.Error("el anónimo que escribió no lee de la primaria")
+	}
+	if router.ReadPool(reqctx.WithClientIP(tenant, "10.0.0.2")) == primary {
+		t.Error("otra IP quedó fijada a la primaria")
+	}
+	router.MarkWrite(tenant)
+	if router.ReadPool(tenant) == primary {
+		t.Error("un contexto sin usuario ni IP quedó fijado a la primaria")
+	}
+}
+
+func TestReplicaRouterPinExpires(t *testing.T) {
+	const window = 30 * time.Millisecond
+	router, primary := newTestRouter(t, ReplicaOptions{ReadYourWrites: window}, "r1")
+	user := reqctx.WithUserID(reqctx.WithTenantID(context.Background(), "acme"), 1)
+
+	router.MarkWrite(user)
+	if router.ReadPool(user) != primary {
+		t.Fatal("la escritura no fijó al usuario a la primaria")
+	}
+
+	time.Sleep(window + 10*time.Millisecond)
+	if router.ReadPool(user) == primary {
+		t.Error("la fijación no venció")
+	}
+
+	router.purgePins()
+	router.mu.Lock()
+	pins := len(router.pins)
+	router.mu.Unlock()
+	if pins != 0 {
+		t.Errorf("purgePins dejó %d fijaciones vencidas", pins)
+	}
+}
+
+func TestReplicaRouterReadYourWritesDisabled(t *testing.T) {
+	router, primary := newTestRouter(t, ReplicaOptions{}, "r1")
+	user := reqctx.WithUserID(reqctx.WithTenantID(context.Background(), "acme"), 1)
+
+	router.MarkWrite(user)
+	if router.ReadPool(user) == primary {
+		t.Error("con ReadYourWrites = 0 no se fija a la primaria")
+	}
+}
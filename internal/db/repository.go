@@ -2,16 +2,21 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrNoteNotFound indica que la nota no existe o no es accesible para el usuario
+var ErrNoteNotFound = errors.New("nota no encontrada")
+
 type Repository interface {
 	CreateNote(ctx context.Context, note *models.CreateNoteRequest) (*models.Note, error)
 	GetNoteByID(ctx context.Context, id int64) (*models.Note, error)
@@ -24,43 +29,130 @@ type Repository interface {
 }
 
 type PostgresRepository struct {
-	pool *pgxpool.Pool
+	pool    *pgxpool.Pool
+	replica *ReplicaRouter // nil: todas las consultas van a la primaria
 }
 
 func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 	return &PostgresRepository{pool: pool}
 }
 
-// CreateNote crea una nueva nota (usando prepared statement)
+// WithReplicas retorna una copia del repositorio que envía GetNoteByID, GetNotesBatch,
+// ListNotes, SearchNotes y GetStats a las réplicas del router
+func (r *PostgresRepository) WithReplicas(router *ReplicaRouter) *PostgresRepository {
+	clone := *r
+	clone.replica = router
+	return &clone
+}
+
+// readPool elige el pool para una lectura que tolera réplicas
+func (r *PostgresRepository) readPool(ctx context.Context) *pgxpool.Pool {
+	if r.replica == nil {
+		return r.pool
+	}
+	return r.replica.ReadPool(ctx)
+}
+
+// readsFromPrimary indica si las lecturas del cliente van a la primaria por read-your-writes
+func (r *PostgresRepository) readsFromPrimary(ctx context.Context) bool {
+	return r.replica != nil && r.replica.pinned(ctx)
+}
+
+// markWrite registra una escritura para read-your-writes
+func (r *PostgresRepository) markWrite(ctx context.Context) {
+	if r.replica != nil {
+		r.replica.MarkWrite(ctx)
+	}
+}
+
+// Condiciones de acceso: las notas sin dueño son visibles para todos,
+// las notas con dueño solo para el dueño y los usuarios con quienes se comparten.
+// El placeholder recibe el ID del usuario del contexto (0 si es anónimo).
+const (
+	visibleToUser = `(owner_id IS NULL OR owner_id = $%[1]d OR EXISTS (
+            SELECT 1 FROM note_shares s WHERE s.note_id = notes.id AND s.user_id = $%[1]d))`
+	editableByUser = `(owner_id IS NULL OR owner_id = $%[1]d OR EXISTS (
+            SELECT 1 FROM note_shares s WHERE s.note_id = notes.id AND s.user_id = $%[1]d AND s.role = 'editor'))`
+	ownedByUser = `(owner_id IS NULL OR owner_id = $%[1]d)`
+)
+
+// Consultas de lectura. Se definen a nivel de paquete para que ExplainQuery
+// analice exactamente el mismo SQL que ejecuta el repositorio.
+var (
+	getNoteByIDQuery = fmt.Sprintf(`
+        SELECT id, owner_id, title, content, created_at, updated_at 
+        FROM notes 
+        WHERE id = $1 AND %s
+    `, fmt.Sprintf(visibleToUser, 2))
+
+	getNotesBatchQuery = fmt.Sprintf(`
+        SELECT id, owner_id, title, content, created_at, updated_at 
+        FROM notes 
+        WHERE id = ANY($1) AND %s
+        ORDER BY id
+    `, fmt.Sprintf(visibleToUser, 2))
+
+	// Keyset pagination: primera página
+	listNotesFirstPageQuery = fmt.Sprintf(`
+        SELECT id, owner_id, title, content, created_at, updated_at 
+        FROM notes 
+        WHERE %s
+        ORDER BY created_at DESC, id DESC 
+        LIMIT $1
+    `, fmt.Sprintf(visibleToUser, 2))
+
+	// Keyset pagination: páginas siguientes a partir del cursor (created_at, id)
+	listNotesNextPageQuery = fmt.Sprintf(`
+        SELECT id, owner_id, title, content, created_at, updated_at 
+        FROM notes 
+        WHERE (created_at, id) < ($1, $2) AND %s
+        ORDER BY created_at DESC, id DESC 
+        LIMIT $3
+    `, fmt.Sprintf(visibleToUser, 4))
+
+	// Búsqueda por título usando el índice GIN
+	searchNotesQuery = fmt.Sprintf(`
+        SELECT id, owner_id, title, content, created_at, updated_at 
+        FROM notes 
+        WHERE to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) AND %s
+        ORDER BY created_at DESC 
+        LIMIT $2
+    `, fmt.Sprintf(visibleToUser, 3))
+)
+
+// CreateNote crea una nueva nota (usando prepared statement) y la registra en auditoría
 func (r *PostgresRepository) CreateNote(ctx context.Context, req *models.CreateNoteRequest) (*models.Note, error) {
 	query := `
-        INSERT INTO notes (title, content) 
-        VALUES ($1, $2) 
-        RETURNING id, title, content, created_at, updated_at
+        INSERT INTO notes (title, content, owner_id) 
+        VALUES ($1, $2, NULLIF($3, 0)) 
+        RETURNING id, owner_id, title, content, created_at, updated_at
     `
 
 	var note models.Note
-	err := r.pool.QueryRow(ctx, query, req.Title, req.Content).
-		Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, query, req.Title, req.Content, reqctx.UserID(ctx)).
+			Scan(&note.ID, &note.OwnerID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, models.AuditCreate, note.ID, nil, &note)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("error creando nota: %w", err)
 	}
 
+	r.markWrite(ctx)
 	return &note, nil
 }
 
-// GetNoteByID obtiene una nota por ID (para cache individual)
+// GetNoteByID obtiene una nota por ID (cacheable con CachedRepository)
 func (r *PostgresRepository) GetNoteByID(ctx context.Context, id int64) (*models.Note, error) {
-	query := `
-        SELECT id, title, content, created_at, updated_at 
-        FROM notes 
-        WHERE id = $1
-    `
-
 	var note models.Note
-	err := r.pool.QueryRow(ctx, query, id).
-		Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	err := r.withReadTenant(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, getNoteByIDQuery, id, reqctx.UserID(ctx)).
+			Scan(&note.ID, &note.OwnerID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	})
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -78,26 +170,17 @@ func (r *PostgresRepository) GetNotesBatch(ctx context.Context, ids []int64) ([]
 		return []models.Note{}, nil
 	}
 
-	query := `
-        SELECT id, title, content, created_at, updated_at 
-        FROM notes 
-        WHERE id = ANY($1)
-        ORDER BY id
-    `
-
-	rows, err := r.pool.Query(ctx, query, ids)
-	if err != nil {
-		return nil, fmt.Errorf("error obteniendo notas batch: %w", err)
-	}
-	defer rows.Close()
-
 	var notes []models.Note
-	for rows.Next() {
-		var note models.Note
-		if err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("error escaneando nota: %w", err)
+	err := r.withReadTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, getNotesBatchQuery, ids, reqctx.UserID(ctx))
+		if err != nil {
+			return fmt.Errorf("error obteniendo notas batch: %w", err)
 		}
-		notes = append(notes, note)
+		notes, err = scanNotes(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return notes, nil
@@ -116,37 +199,24 @@ func (r *PostgresRepository) ListNotes(ctx context.Context, params models.Pagina
 	// Keyset pagination: usar cursor (created_at, id) en lugar de OFFSET
 	if params.CursorTime.IsZero() || params.CursorID == 0 {
 		// Primera página
-		query = `
-            SELECT id, title, content, created_at, updated_at 
-            FROM notes 
-            ORDER BY created_at DESC, id DESC 
-            LIMIT $1
-        `
-		args = []interface{}{params.Limit + 1} // +1 para saber si hay más páginas
+		query = listNotesFirstPageQuery
+		args = []interface{}{params.Limit + 1, reqctx.UserID(ctx)} // +1 para saber si hay más páginas
 	} else {
 		// Páginas siguientes
-		query = `
-            SELECT id, title, content, created_at, updated_at 
-            FROM notes 
-            WHERE (created_at, id) < ($1, $2)
-            ORDER BY created_at DESC, id DESC 
-            LIMIT $3
-        `
-		args = []interface{}{params.CursorTime, params.CursorID, params.Limit + 1}
+		query = listNotesNextPageQuery
+		args = []interface{}{params.CursorTime, params.CursorID, params.Limit + 1, reqctx.UserID(ctx)}
 	}
 
-	rows, err := r.pool.Query(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("error listando notas: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var note models.Note
-		if err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("error escaneando nota: %w", err)
+	err := r.withReadTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("error listando notas: %w", err)
 		}
-		notes = append(notes, note)
+		notes, err = scanNotes(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Determinar si hay siguiente página
@@ -177,27 +247,17 @@ func (r *PostgresRepository) SearchNotes(ctx context.Context, query string, limi
 		limit = 10
 	}
 
-	sqlQuery := `
-        SELECT id, title, content, created_at, updated_at 
-        FROM notes 
-        WHERE to_tsvector('simple', title) @@ plainto_tsquery('simple', $1)
-        ORDER BY created_at DESC 
-        LIMIT $2
-    `
-
-	rows, err := r.pool.Query(ctx, sqlQuery, query, limit)
-	if err != nil {
-		return nil, fmt.Errorf("error buscando notas: %w", err)
-	}
-	defer rows.Close()
-
 	var notes []models.Note
-	for rows.Next() {
-		var note models.Note
-		if err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("error escaneando nota: %w", err)
+	err := r.withReadTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, searchNotesQuery, query, limit, reqctx.UserID(ctx))
+		if err != nil {
+			return fmt.Errorf("error buscando notas: %w", err)
 		}
-		notes = append(notes, note)
+		notes, err = scanNotes(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return notes, nil
@@ -232,13 +292,34 @@ func (r *PostgresRepository) UpdateNote(ctx context.Context, id int64, update mo
 	query := fmt.Sprintf(`
         UPDATE notes 
         SET %s 
-        WHERE id = $%d 
-        RETURNING id, title, content, created_at, updated_at
+        WHERE id = $%d
+        RETURNING id, owner_id, title, content, created_at, updated_at
     `, strings.Join(setClauses, ", "), argIndex)
 
+	// El estado anterior se bloquea con FOR UPDATE para calcular el hash de auditoría
+	lockQuery := fmt.Sprintf(`
+        SELECT id, owner_id, title, content, created_at, updated_at 
+        FROM notes 
+        WHERE id = $1 AND %s
+        FOR UPDATE
+    `, fmt.Sprintf(editableByUser, 2))
+
 	var note models.Note
-	err := r.pool.QueryRow(ctx, query, args...).
-		Scan(&note.ID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		var before models.Note
+		err := tx.QueryRow(ctx, lockQuery, id, reqctx.UserID(ctx)).
+			Scan(&before.ID, &before.OwnerID, &before.Title, &before.Content, &before.CreatedAt, &before.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, query, args...).
+			Scan(&note.ID, &note.OwnerID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, models.AuditUpdate, id, &before, &note)
+	})
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -247,22 +328,36 @@ func (r *PostgresRepository) UpdateNote(ctx context.Context, id int64, update mo
 		return nil, fmt.Errorf("error actualizando nota: %w", err)
 	}
 
+	r.markWrite(ctx)
 	return &note, nil
 }
 
-// DeleteNote elimina una nota
+// DeleteNote elimina una nota y registra su último estado en auditoría
 func (r *PostgresRepository) DeleteNote(ctx context.Context, id int64) error {
-	query := `DELETE FROM notes WHERE id = $1`
+	query := fmt.Sprintf(`
+        DELETE FROM notes 
+        WHERE id = $1 AND %s
+        RETURNING id, owner_id, title, content, created_at, updated_at
+    `, fmt.Sprintf(ownedByUser, 2))
+
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		var before models.Note
+		err := tx.QueryRow(ctx, query, id, reqctx.UserID(ctx)).
+			Scan(&before.ID, &before.OwnerID, &before.Title, &before.Content, &before.CreatedAt, &before.UpdatedAt)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrNoteNotFound
+			}
+			return fmt.Errorf("error eliminando nota: %w", err)
+		}
 
-	result, err := r.pool.Exec(ctx, query, id)
+		return recordAudit(ctx, tx, models.AuditDelete, id, &before, nil)
+	})
 	if err != nil {
-		return fmt.Errorf("error eliminando nota: %w", err)
-	}
-
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("nota no encontrada")
+		return err
 	}
 
+	r.markWrite(ctx)
 	return nil
 }
 
@@ -281,6 +376,9 @@ func (r *PostgresRepository) GetStats(ctx context.Context) (map[string]interface
 		"empty_acquire":      poolStats.EmptyAcquireCount(),
 		"canceled_acquire":   poolStats.CanceledAcquireCount(),
 	}
+	if r.replica != nil {
+		stats["replicas"] = r.replica.Status()
+	}
 
 	// Estadísticas de pg_stat_statements (si está habilitado)
 	query := `
@@ -296,7 +394,8 @@ func (r *PostgresRepository) GetStats(ctx context.Context) (map[string]interface
         LIMIT 10
     `
 
-	rows, err := r.pool.Query(ctx, query)
+	// pg_stat_statements es por servidor: con réplicas muestra las sentencias de la réplica elegida
+	rows, err := r.readPool(ctx).Query(ctx, query)
 	if err != nil {
 		// Si falla, pg_stat_statements no está habilitado
 		stats["query_stats"] = "pg_stat_statements no habilitado"
@@ -332,3 +431,23 @@ func (r *PostgresRepository) GetStats(ctx context.Context) (map[string]interface
 
 	return stats, nil
 }
+
+// scanNotes lee todas las filas (id, owner_id, title, content, created_at, updated_at)
+func scanNotes(rows pgx.Rows) ([]models.Note, error) {
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(&note.ID, &note.OwnerID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error escaneando nota: %w", err)
+		}
+		notes = append(notes, note)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error leyendo notas: %w", err)
+	}
+
+	return notes, nil
+}
@@ -0,0 +1,331 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrShareNotFound indica que la nota no está compartida con ese usuario
+var ErrShareNotFound = errors.New("compartición no encontrada")
+
+// ErrLinkNotFound indica que el enlace público no existe o ya fue revocado
+var ErrLinkNotFound = errors.New("enlace no encontrado")
+
+// SharingRepository gestiona la colaboración sobre notas con dueño.
+// Todas las operaciones de gestión exigen que el usuario del contexto sea el dueño.
+type SharingRepository interface {
+	ShareNote(ctx context.Context, noteID int64, req models.ShareNoteRequest) (*models.NoteShare, error)
+	ListShares(ctx context.Context, noteID int64) ([]models.NoteShare, error)
+	UnshareNote(ctx context.Context, noteID, userID int64) error
+	ListSharedWithMe(ctx context.Context) ([]models.SharedNote, error)
+	CreatePublicLink(ctx context.Context, noteID int64, expiresAt *time.Time) (*models.PublicLink, error)
+	ListPublicLinks(ctx context.Context, noteID int64) ([]models.PublicLink, error)
+	RevokePublicLink(ctx context.Context, noteID, linkID int64) error
+	GetNoteByPublicToken(ctx context.Context, token string) (*models.Note, error)
+}
+
+// ShareNote comparte una nota con otro usuario (o cambia su rol si ya estaba compartida)
+func (r *PostgresRepository) ShareNote(ctx context.Context, noteID int64, req models.ShareNoteRequest) (*models.NoteShare, error) {
+	query := `
+        INSERT INTO note_shares (note_id, user_id, role)
+        SELECT id, $2, $3 FROM notes WHERE id = $1 AND owner_id = $4
+        ON CONFLICT (note_id, user_id) DO UPDATE SET role = EXCLUDED.role
+        RETURNING note_id, user_id, role, created_at
+    `
+
+	var share models.NoteShare
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, noteID, req.UserID, req.Role, reqctx.UserID(ctx)).
+			Scan(&share.NoteID, &share.UserID, &share.Role, &share.CreatedAt)
+	})
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("error compartiendo nota: %w", err)
+	}
+
+	r.markWrite(ctx)
+	return &share, nil
+}
+
+// ListShares lista los usuarios con acceso a una nota propia
+func (r *PostgresRepository) ListShares(ctx context.Context, noteID int64) ([]models.NoteShare, error) {
+	query := `
+        SELECT note_id, user_id, role, created_at
+        FROM note_shares
+        WHERE note_id = $1
+        ORDER BY created_at
+    `
+
+	shares := []models.NoteShare{}
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		if err := ensureOwner(ctx, tx, noteID); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, noteID)
+		if err != nil {
+			return fmt.Errorf("error listando comparticiones: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var share models.NoteShare
+			if err := rows.Scan(&share.NoteID, &share.UserID, &share.Role, &share.CreatedAt); err != nil {
+				return fmt.Errorf("error escaneando compartición: %w", err)
+			}
+			shares = append(shares, share)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+// UnshareNote retira el acceso de un usuario a una nota propia
+func (r *PostgresRepository) UnshareNote(ctx context.Context, noteID, userID int64) error {
+	query := `
+        DELETE FROM note_shares s
+        USING notes n
+        WHERE s.note_id = n.id AND s.note_id = $1 AND s.user_id = $2 AND n.owner_id = $3
+    `
+
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, noteID, userID, reqctx.UserID(ctx))
+		if err != nil {
+			return fmt.Errorf("error retirando compartición: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrShareNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.markWrite(ctx)
+	return nil
+}
+
+// ListSharedWithMe lista las notas que otros usuarios compartieron con el usuario del contexto
+func (r *PostgresRepository) ListSharedWithMe(ctx context.Context) ([]models.SharedNote, error) {
+	query := `
+        SELECT n.id, n.owner_id, n.title, n.content, n.created_at, n.updated_at, s.role
+        FROM note_shares s
+        JOIN notes n ON n.id = s.note_id
+        WHERE s.user_id = $1
+        ORDER BY s.created_at DESC
+    `
+
+	notes := []models.SharedNote{}
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, reqctx.UserID(ctx))
+		if err != nil {
+			return fmt.Errorf("error listando notas compartidas: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var note models.SharedNote
+			if err := rows.Scan(&note.ID, &note.OwnerID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt, &note.Role); err != nil {
+				return fmt.Errorf("error escaneando nota: %w", err)
+			}
+			notes = append(notes, note)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// CreatePublicLink genera un enlace público de solo lectura.
+// Solo se guarda el hash del token: el token en claro se devuelve una única vez.
+func (r *PostgresRepository) CreatePublicLink(ctx context.Context, noteID int64, expiresAt *time.Time) (*models.PublicLink, error) {
+	token, err := newLinkToken()
+	if err != nil {
+		return nil, fmt.Errorf("error generando token: %w", err)
+	}
+
+	query := `
+        INSERT INTO note_links (note_id, token_hash, expires_at)
+        SELECT id, $2, $3 FROM notes WHERE id = $1 AND owner_id = $4
+        RETURNING id, note_id, created_at, expires_at, revoked_at
+    `
+
+	var link models.PublicLink
+	err = r.withTenant(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, noteID, hashLinkToken(token), expiresAt, reqctx.UserID(ctx)).
+			Scan(&link.ID, &link.NoteID, &link.CreatedAt, &link.ExpiresAt, &link.RevokedAt)
+	})
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNoteNotFound
+		}
+		return nil, fmt.Errorf("error creando enlace: %w", err)
+	}
+
+	r.markWrite(ctx)
+	link.Token = token
+	return &link, nil
+}
+
+// ListPublicLinks lista los enlaces públicos de una nota propia (sin tokens)
+func (r *PostgresRepository) ListPublicLinks(ctx context.Context, noteID int64) ([]models.PublicLink, error) {
+	query := `
+        SELECT id, note_id, created_at, expires_at, revoked_at
+        FROM note_links
+        WHERE note_id = $1
+        ORDER BY created_at DESC
+    `
+
+	links := []models.PublicLink{}
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		if err := ensureOwner(ctx, tx, noteID); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, query, noteID)
+		if err != nil {
+			return fmt.Errorf("error listando enlaces: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var link models.PublicLink
+			if err := rows.Scan(&link.ID, &link.NoteID, &link.CreatedAt, &link.ExpiresAt, &link.RevokedAt); err != nil {
+				return fmt.Errorf("error escaneando enlace: %w", err)
+			}
+			links = append(links, link)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// RevokePublicLink revoca un enlace público de una nota propia
+func (r *PostgresRepository) RevokePublicLink(ctx context.Context, noteID, linkID int64) error {
+	query := `
+        UPDATE note_links l
+        SET revoked_at = NOW()
+        FROM notes n
+        WHERE l.note_id = n.id AND l.id = $1 AND l.note_id = $2
+          AND n.owner_id = $3 AND l.revoked_at IS NULL
+    `
+
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, query, linkID, noteID, reqctx.UserID(ctx))
+		if err != nil {
+			return fmt.Errorf("error revocando enlace: %w", err)
+		}
+
+		if result.RowsAffected() == 0 {
+			return ErrLinkNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.markWrite(ctx)
+	return nil
+}
+
+// GetNoteByPublicToken obtiene la nota de un enlace público vigente (nil si no existe).
+// La petición es anónima, así que el tenant se resuelve a partir del propio enlace.
+func (r *PostgresRepository) GetNoteByPublicToken(ctx context.Context, token string) (*models.Note, error) {
+	tokenHash := hashLinkToken(token)
+
+	// note_link_tenant es SECURITY DEFINER: solo revela el tenant del hash dado
+	var tenantID *string
+	if err := r.pool.QueryRow(ctx, `SELECT note_link_tenant($1)`, tokenHash).Scan(&tenantID); err != nil {
+		return nil, fmt.Errorf("error resolviendo enlace: %w", err)
+	}
+	if tenantID == nil {
+		return nil, nil
+	}
+	ctx = reqctx.WithTenantID(ctx, *tenantID)
+
+	query := `
+        SELECT n.id, n.owner_id, n.title, n.content, n.created_at, n.updated_at
+        FROM note_links l
+        JOIN notes n ON n.id = l.note_id
+        WHERE l.token_hash = $1
+          AND l.revoked_at IS NULL
+          AND (l.expires_at IS NULL OR l.expires_at > NOW())
+    `
+
+	var note models.Note
+	err := r.withTenant(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, query, tokenHash).
+			Scan(&note.ID, &note.OwnerID, &note.Title, &note.Content, &note.CreatedAt, &note.UpdatedAt)
+	})
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo nota pública: %w", err)
+	}
+
+	return &note, nil
+}
+
+// ensureOwner verifica que el usuario del contexto sea el dueño de la nota
+func ensureOwner(ctx context.Context, tx pgx.Tx, noteID int64) error {
+	var owned bool
+	err := tx.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM notes WHERE id = $1 AND owner_id = $2)`,
+		noteID, reqctx.UserID(ctx)).Scan(&owned)
+	if err != nil {
+		return fmt.Errorf("error verificando dueño: %w", err)
+	}
+
+	if !owned {
+		return ErrNoteNotFound
+	}
+
+	return nil
+}
+
+// newLinkToken genera un token aleatorio de 256 bits
+func newLinkToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+)
+
+func TestSharingRequiresOwner(t *testing.T) {
+	repo := newTestRepository(t)
+
+	tenant := tenantContext(fmt.Sprintf("share-%d", time.Now().UnixNano()))
+	owner := reqctx.WithUserID(tenant, 1)
+	other := reqctx.WithUserID(tenant, 2)
+
+	note, err := repo.CreateNote(owner, &models.CreateNoteRequest{Title: "privada", Content: "solo el dueño"})
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.DeleteNote(owner, note.ID) })
+
+	link, err := repo.CreatePublicLink(owner, note.ID, nil)
+	if err != nil {
+		t.Fatalf("CreatePublicLink: %v", err)
+	}
+
+	// Para quien no es dueño la nota no existe
+	if _, err := repo.ShareNote(other, note.ID, models.ShareNoteRequest{UserID: 3, Role: models.RoleEditor}); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("ShareNote de otro usuario: se esperaba ErrNoteNotFound, se obtuvo %v", err)
+	}
+	if _, err := repo.ListShares(other, note.ID); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("ListShares de otro usuario: se esperaba ErrNoteNotFound, se obtuvo %v", err)
+	}
+	if _, err := repo.CreatePublicLink(other, note.ID, nil); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("CreatePublicLink de otro usuario: se esperaba ErrNoteNotFound, se obtuvo %v", err)
+	}
+	if _, err := repo.ListPublicLinks(other, note.ID); !errors.Is(err, ErrNoteNotFound) {
+		t.Errorf("ListPublicLinks de otro usuario: se esperaba ErrNoteNotFound, se obtuvo %v", err)
+	}
+	if err := repo.RevokePublicLink(other, note.ID, link.ID); !errors.Is(err, ErrLinkNotFound) {
+		t.Errorf("RevokePublicLink de otro usuario: se esperaba ErrLinkNotFound, se obtuvo %v", err)
+	}
+}
+
+func TestShareAndUnshare(t *testing.T) {
+	repo := newTestRepository(t)
+
+	tenant := tenantContext(fmt.Sprintf("share-%d", time.Now().UnixNano()))
+	owner := reqctx.WithUserID(tenant, 1)
+	viewer := reqctx.WithUserID(tenant, 2)
+
+	note, err := repo.CreateNote(owner, &models.CreateNoteRequest{Title: "compartida", Content: "para el viewer"})
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.DeleteNote(owner, note.ID) })
+
+	if got, err := repo.GetNoteByID(viewer, note.ID); err != nil || got != nil {
+		t.Fatalf("el viewer ve la nota antes de compartirla: %v, %v", got, err)
+	}
+
+	if _, err := repo.ShareNote(owner, note.ID, models.ShareNoteRequest{UserID: 2, Role: models.RoleViewer}); err != nil {
+		t.Fatalf("ShareNote: %v", err)
+	}
+
+	if got, err := repo.GetNoteByID(viewer, note.ID); err != nil || got == nil {
+		t.Fatalf("el viewer no ve la nota compartida: %v", err)
+	}
+	shared, err := repo.ListSharedWithMe(viewer)
+	if err != nil || len(shared) != 1 || shared[0].ID != note.ID || shared[0].Role != models.RoleViewer {
+		t.Fatalf("ListSharedWithMe = %+v, %v", shared, err)
+	}
+
+	// viewer no puede editar
+	if got, err := repo.UpdateNote(viewer, note.ID, models.UpdateNoteRequest{Title: "editada"}); err != nil || got != nil {
+		t.Fatalf("el viewer pudo editar la nota: %v, %v", got, err)
+	}
+
+	if err := repo.UnshareNote(owner, note.ID, 2); err != nil {
+		t.Fatalf("UnshareNote: %v", err)
+	}
+	if got, err := repo.GetNoteByID(viewer, note.ID); err != nil || got != nil {
+		t.Fatalf("el viewer ve la nota después de retirarle el acceso: %v, %v", got, err)
+	}
+	if err := repo.UnshareNote(owner, note.ID, 2); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("UnshareNote repetido: se esperaba ErrShareNotFound, se obtuvo %v", err)
+	}
+}
+
+func TestPublicLinks(t *testing.T) {
+	repo := newTestRepository(t)
+
+	tenant := tenantContext(fmt.Sprintf("links-%d", time.Now().UnixNano()))
+	owner := reqctx.WithUserID(tenant, 1)
+
+	note, err := repo.CreateNote(owner, &models.CreateNoteRequest{Title: "pública", Content: "por enlace"})
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.DeleteNote(owner, note.ID) })
+
+	// Los enlaces públicos son anónimos: sin tenant ni usuario
+	anonymous := t.Context()
+
+	t.Run("Vigente", func(t *testing.T) {
+		link, err := repo.CreatePublicLink(owner, note.ID, nil)
+		if err != nil {
+			t.Fatalf("CreatePublicLink: %v", err)
+		}
+		got, err := repo.GetNoteByPublicToken(anonymous, link.Token)
+		if err != nil || got == nil || got.ID != note.ID {
+			t.Fatalf("GetNoteByPublicToken = %+v, %v", got, err)
+		}
+	})
+
+	t.Run("Revocado", func(t *testing.T) {
+		link, err := repo.CreatePublicLink(owner, note.ID, nil)
+		if err != nil {
+			t.Fatalf("CreatePublicLink: %v", err)
+		}
+		if err := repo.RevokePublicLink(owner, note.ID, link.ID); err != nil {
+			t.Fatalf("RevokePublicLink: %v", err)
+		}
+		if got, err := repo.GetNoteByPublicToken(anonymous, link.Token); err != nil || got != nil {
+			t.Fatalf("un enlace revocado sigue mostrando la nota: %+v, %v", got, err)
+		}
+		if err := repo.RevokePublicLink(owner, note.ID, link.ID); !errors.Is(err, ErrLinkNotFound) {
+			t.Errorf("revocar dos veces: se esperaba ErrLinkNotFound, se obtuvo %v", err)
+		}
+	})
+
+	t.Run("Vencido", func(t *testing.T) {
+		expired := time.Now().Add(-time.Minute)
+		link, err := repo.CreatePublicLink(owner, note.ID, &expired)
+		if err != nil {
+			t.Fatalf("CreatePublicLink: %v", err)
+		}
+		if got, err := repo.GetNoteByPublicToken(anonymous, link.Token); err != nil || got != nil {
+			t.Fatalf("un enlace vencido sigue mostrando la nota: %+v, %v", got, err)
+		}
+	})
+
+	t.Run("Desconocido", func(t *testing.T) {
+		if got, err := repo.GetNoteByPublicToken(anonymous, "no-existe"); err != nil || got != nil {
+			t.Fatalf("token desconocido = %+v, %v", got, err)
+		}
+	})
+}
+
+// Las escrituras de colaboración también fijan al dueño a la primaria
+func TestSharingMarksWrite(t *testing.T) {
+	base := newTestRepository(t)
+	router := NewReplicaRouter(base.pool, nil, ReplicaOptions{ReadYourWrites: time.Minute}, slog.New(slog.DiscardHandler))
+	repo := base.WithReplicas(router)
+
+	tenant := tenantContext(fmt.Sprintf("pin-%d", time.Now().UnixNano()))
+	owner := reqctx.WithUserID(tenant, 1)
+
+	note, err := base.CreateNote(owner, &models.CreateNoteRequest{Title: "fijada", Content: "primaria"})
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	t.Cleanup(func() { _ = base.DeleteNote(owner, note.ID) })
+
+	var linkID int64
+	writes := []struct {
+		name  string
+		write func() error
+	}{
+		{"ShareNote", func() error {
+			_, err := repo.ShareNote(owner, note.ID, models.ShareNoteRequest{UserID: 2, Role: models.RoleViewer})
+			return err
+		}},
+		{"UnshareNote", func() error { return repo.UnshareNote(owner, note.ID, 2) }},
+		{"CreatePublicLink", func() error {
+			link, err := repo.CreatePublicLink(owner, note.ID, nil)
+			if link != nil {
+				linkID = link.ID
+			}
+			return err
+		}},
+		{"RevokePublicLink", func() error { return repo.RevokePublicLink(owner, note.ID, linkID) }},
+	}
+
+	for _, w := range writes {
+		// Sin fijaciones previas: cada escritura debe fijar por sí misma
+		router.mu.Lock()
+		clear(router.pins)
+		router.mu.Unlock()
+
+		if err := w.write(); err != nil {
+			t.Fatalf("%s: %v", w.name, err)
+		}
+		if !repo.readsFromPrimary(owner) {
+			t.Errorf("%s no fijó al dueño a la primaria", w.name)
+		}
+	}
+}
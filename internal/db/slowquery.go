@@ -0,0 +1,277 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SlowQueryRepository consulta los planes capturados de consultas lentas
+type SlowQueryRepository interface {
+	ListSlowQueries(ctx context.Context, limit int) ([]models.SlowQuery, error)
+	GetSlowQuery(ctx context.Context, id int64) (*models.SlowQuery, error)
+}
+
+// Sentencias para las que no tiene sentido (o no es posible) pedir EXPLAIN
+var unexplainablePrefixes = []string{"EXPLAIN", "BEGIN", "COMMIT", "ROLLBACK", "SET", "SELECT SET_CONFIG", "DEALLOCATE"}
+
+// explainCooldown evita capturar el plan de la misma sentencia más de una vez por intervalo
+const explainCooldown = time.Minute
+
+// SlowQueryLog es un pgx.QueryTracer que registra las sentencias que superan el umbral
+// y, opcionalmente, guarda su EXPLAIN (FORMAT JSON) en slow_query_plans.
+// Los argumentos nunca se registran: solo su tipo y tamaño.
+type SlowQueryLog struct {
+	threshold time.Duration
+	explain   bool
+	logger    *slog.Logger
+	pool      *pgxpool.Pool
+	queue     chan slowQuery
+
+	mu        sync.Mutex
+	explained map[string]time.Time
+}
+
+type slowQueryKey struct{}
+
+// explainKey marca el contexto de las consultas del propio SlowQueryLog para no trazarlas
+type explainKey struct{}
+
+type slowQueryStart struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+type slowQuery struct {
+	sql       string
+	args      []any
+	duration  time.Duration
+	requestID string
+	tenantID  string
+}
+
+// NewSlowQueryLog crea el tracer; threshold <= 0 lo deshabilita
+func NewSlowQueryLog(threshold time.Duration, explain bool, logger *slog.Logger) *SlowQueryLog {
+	return &SlowQueryLog{
+		threshold: threshold,
+		explain:   explain,
+		logger:    logger,
+		queue:     make(chan slowQuery, 64),
+		explained: make(map[string]time.Time),
+	}
+}
+
+// Start inicia la captura de planes usando el pool (ya creado con este tracer)
+func (l *SlowQueryLog) Start(ctx context.Context, pool *pgxpool.Pool) {
+	l.pool = pool
+	if l.explain {
+		go l.captureLoop(ctx)
+	}
+}
+
+func (l *SlowQueryLog) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if l.threshold <= 0 || ctx.Value(explainKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, slowQueryKey{}, slowQueryStart{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+func (l *SlowQueryLog) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(slowQueryKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+
+	elapsed := time.Since(start.start)
+	if elapsed < l.threshold {
+		return
+	}
+
+	l.logger.WarnContext(ctx, "consulta lenta",
+		slog.String("sql", compactSQL(start.sql)),
+		slog.Duration("duration", elapsed),
+		slog.Duration("threshold", l.threshold),
+		slog.Any("args", redactArgs(start.args)),
+		slog.Bool("failed", data.Err != nil))
+
+	if !l.explain || data.Err != nil || !explainable(start.sql) || !l.shouldExplain(start.sql) {
+		return
+	}
+
+	select {
+	case l.queue <- slowQuery{
+		sql:       start.sql,
+		args:      start.args,
+		duration:  elapsed,
+		requestID: reqctx.RequestID(ctx),
+		tenantID:  reqctx.TenantID(ctx),
+	}:
+	default:
+		// Cola llena: se descarta la captura para no frenar las consultas
+	}
+}
+
+// shouldExplain aplica el cooldown por sentencia
+func (l *SlowQueryLog) shouldExplain(sql string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := l.explained[sql]; ok && now.Sub(last) < explainCooldown {
+		return false
+	}
+	l.explained[sql] = now
+
+	// Limpieza de entradas viejas para que el mapa no crezca sin límite
+	if len(l.explained) > 1000 {
+		for k, t := range l.explained {
+			if now.Sub(t) >= explainCooldown {
+				delete(l.explained, k)
+			}
+		}
+	}
+	return true
+}
+
+func (l *SlowQueryLog) captureLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-l.queue:
+			if err := l.capture(ctx, q); err != nil {
+				l.logger.Warn("error capturando EXPLAIN de consulta lenta",
+					slog.String("error", err.Error()),
+					slog.String("request_id", q.requestID))
+			}
+		}
+	}
+}
+
+// capture ejecuta EXPLAIN (sin ANALYZE: la sentencia no se vuelve a ejecutar)
+// con los mismos argumentos y guarda el plan
+func (l *SlowQueryLog) capture(ctx context.Context, q slowQuery) error {
+	ctx = context.WithValue(ctx, explainKey{}, true)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tx, err := l.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Las políticas RLS forman parte del plan, así que se explica con el mismo tenant
+	if q.tenantID != "" {
+		if _, err := tx.Exec(ctx, `SELECT set_config('app.tenant_id', $1, true)`, q.tenantID); err != nil {
+			return err
+		}
+	}
+
+	var plan []byte
+	if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+q.sql, q.args...).Scan(&plan); err != nil {
+		return fmt.Errorf("EXPLAIN: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+        INSERT INTO slow_query_plans (query, duration_ms, plan, request_id, tenant_id)
+        VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+    `, compactSQL(q.sql), float64(q.duration.Microseconds())/1000, plan, q.requestID, q.tenantID)
+	if err != nil {
+		return fmt.Errorf("guardando plan: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListSlowQueries lista las últimas consultas lentas capturadas (sin el plan)
+func (l *SlowQueryLog) ListSlowQueries(ctx context.Context, limit int) ([]models.SlowQuery, error) {
+	query := `
+        SELECT id, query, duration_ms, COALESCE(request_id, ''), COALESCE(tenant_id, ''), captured_at
+        FROM slow_query_plans
+        ORDER BY id DESC
+        LIMIT $1
+    `
+
+	rows, err := l.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listando consultas lentas: %w", err)
+	}
+	defer rows.Close()
+
+	queries := []models.SlowQuery{}
+	for rows.Next() {
+		var q models.SlowQuery
+		if err := rows.Scan(&q.ID, &q.Query, &q.DurationMs, &q.RequestID, &q.TenantID, &q.CapturedAt); err != nil {
+			return nil, fmt.Errorf("error escaneando consulta lenta: %w", err)
+		}
+		queries = append(queries, q)
+	}
+
+	return queries, rows.Err()
+}
+
+// GetSlowQuery obtiene una consulta lenta con su plan (nil si no existe)
+func (l *SlowQueryLog) GetSlowQuery(ctx context.Context, id int64) (*models.SlowQuery, error) {
+	query := `
+        SELECT id, query, duration_ms, COALESCE(request_id, ''), COALESCE(tenant_id, ''), captured_at, plan
+        FROM slow_query_plans
+        WHERE id = $1
+    `
+
+	var q models.SlowQuery
+	var plan []byte
+	err := l.pool.QueryRow(ctx, query, id).
+		Scan(&q.ID, &q.Query, &q.DurationMs, &q.RequestID, &q.TenantID, &q.CapturedAt, &plan)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error obteniendo consulta lenta: %w", err)
+	}
+
+	q.Plan = json.RawMessage(plan)
+	return &q, nil
+}
+
+func explainable(sql string) bool {
+	head := strings.ToUpper(strings.TrimSpace(sql))
+	for _, prefix := range unexplainablePrefixes {
+		if strings.HasPrefix(head, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// redactArgs describe los argumentos por tipo y tamaño, sin sus valores
+func redactArgs(args []any) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			redacted[i] = "NULL"
+			continue
+		}
+		v := reflect.ValueOf(arg)
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			redacted[i] = fmt.Sprintf("%T(len=%d)", arg, v.Len())
+		default:
+			redacted[i] = fmt.Sprintf("%T", arg)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrMissingTenant indica que el contexto no tiene tenant y no se puede consultar
+var ErrMissingTenant = errors.New("tenant no definido")
+
+// withTenant ejecuta fn en una transacción con app.tenant_id fijado al tenant del contexto.
+// Las políticas RLS (ver internal/migrations) filtran por esa variable, así que aunque una consulta
+// olvide filtrar por tenant no puede ver filas de otro workspace.
+// Siempre usa la primaria; las lecturas que toleran réplicas usan withReadTenant.
+func (r *PostgresRepository) withTenant(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return inTenant(ctx, r.pool, fn)
+}
+
+// withReadTenant es withTenant sobre el pool que elija el router de réplicas (o la primaria)
+func (r *PostgresRepository) withReadTenant(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return inTenant(ctx, r.readPool(ctx), fn)
+}
+
+func inTenant(ctx context.Context, pool *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	tenantID := reqctx.TenantID(ctx)
+	if tenantID == "" {
+		return ErrMissingTenant
+	}
+
+	conn, err := acquireConn(ctx, pool)
+	if err != nil {
+		return fmt.Errorf("error adquiriendo conexión: %w", err)
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error iniciando transacción: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// is_local = true: la variable solo vive hasta el fin de la transacción
+	if _, err := tx.Exec(ctx, `SELECT set_config('app.tenant_id', $1, true)`, tenantID); err != nil {
+		return fmt.Errorf("error fijando tenant: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error confirmando transacción: %w", err)
+	}
+
+	return nil
+}
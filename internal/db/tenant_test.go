@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Pruebas de integración: requieren una base con las migraciones aplicadas y
+// TEST_DATABASE_URL apuntando a un rol sin privilegios (p. ej. notes_app).
+func newTestRepository(t *testing.T) *PostgresRepository {
+	t.Helper()
+
+	connString := os.Getenv("TEST_DATABASE_URL")
+	if connString == "" {
+		t.Skip("TEST_DATABASE_URL no definido")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		t.Fatalf("error conectando: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	var bypass bool
+	err = pool.QueryRow(ctx,
+		`SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`).Scan(&bypass)
+	if err != nil {
+		t.Fatalf("error consultando rol: %v", err)
+	}
+	if bypass {
+		t.Fatal("TEST_DATABASE_URL usa un rol que ignora RLS; use notes_app")
+	}
+
+	return NewPostgresRepository(pool)
+}
+
+func tenantContext(tenantID string) context.Context {
+	return reqctx.WithTenantID(context.Background(), tenantID)
+}
+
+func TestTenantIsolation(t *testing.T) {
+	repo := newTestRepository(t)
+
+	suffix := time.Now().UnixNano()
+	acme := tenantContext(fmt.Sprintf("acme-%d", suffix))
+	globex := tenantContext(fmt.Sprintf("globex-%d", suffix))
+
+	title := fmt.Sprintf("secreto %d", suffix)
+	note, err := repo.CreateNote(acme, &models.CreateNoteRequest{Title: title, Content: "solo acme"})
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	t.Cleanup(func() { _ = repo.DeleteNote(acme, note.ID) })
+
+	t.Run("GetNoteByID", func(t *testing.T) {
+		got, err := repo.GetNoteByID(globex, note.ID)
+		if err != nil {
+			t.Fatalf("GetNoteByID: %v", err)
+		}
+		if got != nil {
+			t.Fatalf("globex ve la nota %d de acme", note.ID)
+		}
+
+		got, err = repo.GetNoteByID(acme, note.ID)
+		if err != nil || got == nil {
+			t.Fatalf("acme no ve su propia nota: %v", err)
+		}
+	})
+
+	t.Run("GetNotesBatch", func(t *testing.T) {
+		notes, err := repo.GetNotesBatch(globex, []int64{note.ID})
+		if err != nil {
+			t.Fatalf("GetNotesBatch: %v", err)
+		}
+		if len(notes) != 0 {
+			t.Fatalf("globex obtuvo %d notas de acme", len(notes))
+		}
+	})
+
+	t.Run("ListNotes", func(t *testing.T) {
+		page, err := repo.ListNotes(globex, models.PaginationParams{Limit: 100})
+		if err != nil {
+			t.Fatalf("ListNotes: %v", err)
+		}
+		for _, n := range page.Notes {
+			if n.ID == note.ID {
+				t.Fatalf("ListNotes de globex incluye la nota %d de acme", note.ID)
+			}
+		}
+	})
+
+	t.Run("SearchNotes", func(t *testing.T) {
+		notes, err := repo.SearchNotes(globex, title, 10)
+		if err != nil {
+			t.Fatalf("SearchNotes: %v", err)
+		}
+		if len(notes) != 0 {
+			t.Fatalf("SearchNotes de globex encontró %d notas de acme", len(notes))
+		}
+	})
+
+	t.Run("UpdateNote", func(t *testing.T) {
+		got, err := repo.UpdateNote(globex, note.ID, models.UpdateNoteRequest{Title: "pwned"})
+		if err != nil {
+			t.Fatalf("UpdateNote: %v", err)
+		}
+		if got != nil {
+			t.Fatal("globex pudo actualizar la nota de acme")
+		}
+	})
+
+	t.Run("DeleteNote", func(t *testing.T) {
+		if err := repo.DeleteNote(globex, note.ID); !errors.Is(err, ErrNoteNotFound) {
+			t.Fatalf("DeleteNote de globex: se esperaba ErrNoteNotFound, se obtuvo %v", err)
+		}
+	})
+
+	// Una consulta "con bug" que no filtra por tenant tampoco ve filas ajenas
+	t.Run("UnfilteredQuery", func(t *testing.T) {
+		var count int
+		err := repo.withTenant(globex, func(tx pgx.Tx) error {
+			return tx.QueryRow(context.Background(), `SELECT count(*) FROM notes WHERE id = $1`, note.ID).Scan(&count)
+		})
+		if err != nil {
+			t.Fatalf("consulta sin filtro: %v", err)
+		}
+		if count != 0 {
+			t.Fatalf("consulta sin filtro de globex ve %d filas de acme", count)
+		}
+	})
+
+	// WITH CHECK impide escribir filas con el tenant de otro workspace
+	t.Run("ForeignTenantInsert", func(t *testing.T) {
+		err := repo.withTenant(globex, func(tx pgx.Tx) error {
+			_, err := tx.Exec(context.Background(),
+				`INSERT INTO notes (tenant_id, title, content) VALUES ($1, 'x', 'x')`,
+				reqctx.TenantID(acme))
+			return err
+		})
+		if err == nil {
+			t.Fatal("globex pudo insertar una nota en el tenant de acme")
+		}
+	})
+}
+
+func TestMissingTenant(t *testing.T) {
+	repo := newTestRepository(t)
+
+	if _, err := repo.GetNoteByID(context.Background(), 1); !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("se esperaba ErrMissingTenant, se obtuvo %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package gqlapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+)
+
+// Códigos de extensions.code, los habituales en clientes GraphQL
+const (
+	codeBadUserInput    = "BAD_USER_INPUT"
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeNotFound        = "NOT_FOUND"
+	codeInternal        = "INTERNAL_SERVER_ERROR"
+)
+
+// resolverError es un error con código en extensions (graphql-go lo copia a la respuesta)
+type resolverError struct {
+	message string
+	code    string
+}
+
+func (e *resolverError) Error() string {
+	return e.message
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func userError(message string) error {
+	return &resolverError{message: message, code: codeBadUserInput}
+}
+
+func unauthenticatedError() error {
+	return &resolverError{message: "Se requiere usuario", code: codeUnauthenticated}
+}
+
+func notFoundError() error {
+	return &resolverError{message: "Nota no encontrada", code: codeNotFound}
+}
+
+// toError traduce los errores del repositorio. Los errores internos se registran
+// y el cliente recibe un mensaje genérico, igual que en REST.
+func toError(ctx context.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, db.ErrNoteNotFound):
+		return notFoundError()
+	case errors.Is(err, db.ErrMissingTenant):
+		return userError("Tenant no definido")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	}
+
+	slog.ErrorContext(ctx, message, "error", err)
+	return &resolverError{message: message, code: codeInternal}
+}
@@ -0,0 +1,87 @@
+// Package gqlapi expone las notas por GraphQL (POST /graphql) sobre el mismo
+// db.Repository que las rutas REST. Las lecturas de notas por ID pasan por un
+// dataloader por petición que las agrupa en GetNotesBatch.
+package gqlapi
+
+import (
+	"context"
+	_ "embed"
+	"log/slog"
+	"net/http"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Options configura los límites de ejecución de las consultas
+type Options struct {
+	// MaxDepth rechaza consultas más profundas (0: sin límite)
+	MaxDepth int
+	// MaxParallelism acota los resolvers que corren en paralelo por consulta
+	MaxParallelism int
+	Logger         *slog.Logger
+}
+
+// API es el endpoint GraphQL
+type API struct {
+	schema *graphql.Schema
+	notes  db.Repository
+}
+
+// New valida el esquema contra los resolvers; falla al arrancar si no coinciden
+func New(notes db.Repository, sharing db.SharingRepository, opts Options) (*API, error) {
+	schemaOpts := []graphql.SchemaOpt{
+		graphql.UseStringDescriptions(),
+		graphql.Logger(panicLogger{opts.Logger}),
+	}
+	if opts.MaxDepth > 0 {
+		schemaOpts = append(schemaOpts, graphql.MaxDepth(opts.MaxDepth))
+	}
+	if opts.MaxParallelism > 0 {
+		schemaOpts = append(schemaOpts, graphql.MaxParallelism(opts.MaxParallelism))
+	}
+
+	schema, err := graphql.ParseSchema(schemaSDL, &rootResolver{notes: notes, sharing: sharing}, schemaOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &API{schema: schema, notes: notes}, nil
+}
+
+// GraphQLRequest es el cuerpo de POST /graphql
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Handler atiende POST /graphql. Los errores de la consulta se devuelven con 200
+// en "errors", como indica GraphQL over HTTP; solo un cuerpo inválido da 400.
+func (a *API) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req GraphQLRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := c.Request.Context()
+		ctx = withNoteLoader(ctx, newNoteLoader(ctx, a.notes))
+
+		c.JSON(http.StatusOK, a.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	}
+}
+
+// panicLogger registra los panics de los resolvers (graphql-go los convierte en errores)
+type panicLogger struct {
+	logger *slog.Logger
+}
+
+func (l panicLogger) LogPanic(ctx context.Context, value interface{}) {
+	l.logger.ErrorContext(ctx, "panic en resolver GraphQL", slog.Any("panic", value))
+}
@@ -0,0 +1,175 @@
+package gqlapi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+)
+
+// Parámetros del dataloader: los resolvers de una consulta corren en paralelo,
+// así que las lecturas que llegan dentro de loaderWait se agrupan en un único
+// GetNotesBatch de hasta loaderMaxBatch IDs
+const (
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 100
+)
+
+// noteLoader agrupa las lecturas de notas de una petición GraphQL en llamadas a
+// GetNotesBatch y recuerda los resultados hasta el final de la petición, así una
+// consulta anidada hace una consulta por nivel y no una por nota (N+1).
+// Vive lo que dura la petición: usa su contexto (tenant, usuario) en cada batch.
+type noteLoader struct {
+	ctx  context.Context
+	repo db.Repository
+
+	mu      sync.Mutex
+	results map[int64]*noteResult
+	pending *noteBatch
+}
+
+type noteResult struct {
+	done chan struct{}
+	note *models.Note
+	err  error
+}
+
+type noteBatch struct {
+	ids        []int64
+	dispatched bool
+}
+
+func newNoteLoader(ctx context.Context, repo db.Repository) *noteLoader {
+	return &noteLoader{ctx: ctx, repo: repo, results: make(map[int64]*noteResult)}
+}
+
+// Load retorna la nota (nil si no existe o no es visible para el usuario)
+func (l *noteLoader) Load(ctx context.Context, id int64) (*models.Note, error) {
+	return l.enqueue(id).wait(ctx)
+}
+
+// LoadMany carga varias notas en el mismo batch, en el orden de ids
+func (l *noteLoader) LoadMany(ctx context.Context, ids []int64) ([]*models.Note, error) {
+	results := make([]*noteResult, len(ids))
+	for i, id := range ids {
+		results[i] = l.enqueue(id)
+	}
+
+	notes := make([]*models.Note, len(ids))
+	for i, r := range results {
+		note, err := r.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		notes[i] = note
+	}
+	return notes, nil
+}
+
+// Prime guarda una nota ya leída (listados, búsquedas, mutaciones) para que las
+// lecturas posteriores de la misma petición no vuelvan a la base
+func (l *noteLoader) Prime(note *models.Note) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.results[note.ID]; ok {
+		return
+	}
+	clone := *note
+	r := &noteResult{done: make(chan struct{}), note: &clone}
+	close(r.done)
+	l.results[note.ID] = r
+}
+
+// Forget descarta la nota tras modificarla o eliminarla en la petición
+func (l *noteLoader) Forget(id int64) {
+	l.mu.Lock()
+	delete(l.results, id)
+	l.mu.Unlock()
+}
+
+// enqueue agrega el ID al batch pendiente sin esperar el resultado
+func (l *noteLoader) enqueue(id int64) *noteResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r, ok := l.results[id]; ok {
+		return r
+	}
+	r := &noteResult{done: make(chan struct{})}
+	l.results[id] = r
+
+	if l.pending == nil {
+		b := &noteBatch{}
+		l.pending = b
+		time.AfterFunc(loaderWait, func() { l.dispatch(b) })
+	}
+	l.pending.ids = append(l.pending.ids, id)
+	if len(l.pending.ids) >= loaderMaxBatch {
+		go l.dispatch(l.pending)
+		l.pending = nil
+	}
+	return r
+}
+
+// dispatch ejecuta el batch (una sola vez aunque lo disparen el timer y el tamaño)
+func (l *noteLoader) dispatch(b *noteBatch) {
+	l.mu.Lock()
+	if b.dispatched {
+		l.mu.Unlock()
+		return
+	}
+	b.dispatched = true
+	if l.pending == b {
+		l.pending = nil
+	}
+	results := make([]*noteResult, len(b.ids))
+	for i, id := range b.ids {
+		results[i] = l.results[id]
+	}
+	l.mu.Unlock()
+
+	notes, err := l.repo.GetNotesBatch(l.ctx, b.ids)
+	byID := make(map[int64]*models.Note, len(notes))
+	for i := range notes {
+		byID[notes[i].ID] = &notes[i]
+	}
+
+	if err != nil {
+		// Sin cachear el error: otra lectura de la petición puede reintentar
+		l.mu.Lock()
+		for i, id := range b.ids {
+			if l.results[id] == results[i] {
+				delete(l.results, id)
+			}
+		}
+		l.mu.Unlock()
+	}
+
+	for i, id := range b.ids {
+		results[i].note, results[i].err = byID[id], err
+		close(results[i].done)
+	}
+}
+
+func (r *noteResult) wait(ctx context.Context) (*models.Note, error) {
+	select {
+	case <-r.done:
+		return r.note, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type loaderKey struct{}
+
+func withNoteLoader(ctx context.Context, l *noteLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+func loaderFrom(ctx context.Context) *noteLoader {
+	l, _ := ctx.Value(loaderKey{}).(*noteLoader)
+	return l
+}
@@ -0,0 +1,244 @@
+package gqlapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// batchRepository registra cada llamada a GetNotesBatch; las notas con ID menor
+// que missingFrom existen. Si err está definido falla la próxima llamada.
+type batchRepository struct {
+	db.Repository
+	missingFrom int64
+
+	mu      sync.Mutex
+	batches [][]int64
+	err     error
+	listed  int
+}
+
+func (r *batchRepository) GetNotesBatch(_ context.Context, ids []int64) ([]models.Note, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, slices.Clone(ids))
+	if err := r.err; err != nil {
+		r.err = nil
+		return nil, err
+	}
+
+	notes := []models.Note{}
+	for _, id := range ids {
+		if id < r.missingFrom {
+			notes = append(notes, models.Note{ID: id, Title: fmt.Sprintf("nota %d", id)})
+		}
+	}
+	return notes, nil
+}
+
+func (r *batchRepository) ListNotes(context.Context, models.PaginationParams) (*models.NotesPage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listed++
+	return &models.NotesPage{Notes: []models.Note{}}, nil
+}
+
+func (r *batchRepository) calls() [][]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.batches)
+}
+
+func TestLoaderBatchesConcurrentLoads(t *testing.T) {
+	repo := &batchRepository{missingFrom: 100}
+	loader := newNoteLoader(t.Context(), repo)
+
+	ids := []int64{1, 2, 3, 2, 1, 200}
+	notes := make([]*models.Note, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			note, err := loader.Load(t.Context(), id)
+			if err != nil {
+				t.Errorf("Load(%d): %v", id, err)
+			}
+			notes[i] = note
+		}()
+	}
+	wg.Wait()
+
+	batches := repo.calls()
+	if len(batches) != 1 {
+		t.Fatalf("GetNotesBatch se llamó %d veces (%v), se esperaba 1", len(batches), batches)
+	}
+	got := slices.Sorted(slices.Values(batches[0]))
+	if !slices.Equal(got, []int64{1, 2, 3, 200}) {
+		t.Errorf("IDs del batch = %v, se esperaba cada ID una sola vez", got)
+	}
+	for i, id := range ids {
+		if id == 200 {
+			if notes[i] != nil {
+				t.Errorf("Load(200) = %+v, se esperaba nil", notes[i])
+			}
+		} else if notes[i] == nil || notes[i].ID != id {
+			t.Errorf("Load(%d) = %+v", id, notes[i])
+		}
+	}
+
+	// Los resultados se recuerdan hasta el final de la petición
+	if _, err := loader.Load(t.Context(), 2); err != nil || len(repo.calls()) != 1 {
+		t.Errorf("una lectura repetida volvió a la base")
+	}
+}
+
+func TestLoaderMaxBatch(t *testing.T) {
+	repo := &batchRepository{missingFrom: 1000}
+	loader := newNoteLoader(t.Context(), repo)
+
+	ids := make([]int64, loaderMaxBatch+50)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	notes, err := loader.LoadMany(t.Context(), ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, note := range notes {
+		if note == nil || note.ID != ids[i] {
+			t.Fatalf("LoadMany[%d] = %+v, se esperaba el orden de ids", i, note)
+		}
+	}
+
+	batches := repo.calls()
+	if len(batches) != 2 || len(batches[0])+len(batches[1]) != len(ids) {
+		t.Fatalf("batches = %d, se esperaban 2 que sumen %d IDs", len(batches), len(ids))
+	}
+	for _, b := range batches {
+		if len(b) > loaderMaxBatch {
+			t.Errorf("batch de %d IDs supera loaderMaxBatch", len(b))
+		}
+	}
+}
+
+func TestLoaderPrimeAndForget(t *testing.T) {
+	repo := &batchRepository{missingFrom: 100}
+	loader := newNoteLoader(t.Context(), repo)
+
+	loader.Prime(&models.Note{ID: 5, Title: "leída en el listado"})
+	if note, err := loader.Load(t.Context(), 5); err != nil || note.Title != "leída en el listado" {
+		t.Errorf("Load(5) = %+v, %v", note, err)
+	}
+	if len(repo.calls()) != 0 {
+		t.Fatal("una nota cargada con Prime volvió a la base")
+	}
+
+	loader.Forget(5)
+	if note, err := loader.Load(t.Context(), 5); err != nil || note.Title != "nota 5" {
+		t.Errorf("Load(5) después de Forget = %+v, %v", note, err)
+	}
+	if len(repo.calls()) != 1 {
+		t.Errorf("GetNotesBatch se llamó %d veces, se esperaba 1", len(repo.calls()))
+	}
+}
+
+func TestLoaderDoesNotCacheErrors(t *testing.T) {
+	failure := errors.New("conexión perdida")
+	repo := &batchRepository{missingFrom: 100, err: failure}
+	loader := newNoteLoader(t.Context(), repo)
+
+	if _, err := loader.Load(t.Context(), 1); !errors.Is(err, failure) {
+		t.Fatalf("Load = %v, se esperaba el error del repositorio", err)
+	}
+	if note, err := loader.Load(t.Context(), 1); err != nil || note == nil {
+		t.Errorf("el reintento = %+v, %v", note, err)
+	}
+	if len(repo.calls()) != 2 {
+		t.Errorf("GetNotesBatch se llamó %d veces, se esperaba 2", len(repo.calls()))
+	}
+}
+
+func TestLoaderWaitHonorsContext(t *testing.T) {
+	loader := newNoteLoader(t.Context(), &batchRepository{})
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := loader.Load(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Load con contexto cancelado = %v", err)
+	}
+}
+
+func execGraphQL(t *testing.T, api *API, query string) map[string]any {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/graphql", api.Handler())
+
+	body, _ := json.Marshal(GraphQLRequest{Query: query})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /graphql = %d: %s", w.Code, w.Body)
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// Los campos de nota de un mismo nivel se resuelven en paralelo y salen en un
+// único GetNotesBatch, con cada ID una sola vez
+func TestQueryBatchesNotesPerLevel(t *testing.T) {
+	repo := &batchRepository{missingFrom: 10}
+	api, err := New(repo, nil, Options{MaxDepth: 10, Logger: slog.New(slog.DiscardHandler)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := func(n int64) string { return string(relay.MarshalID(noteKind, n)) }
+	query := fmt.Sprintf(`{
+		a: note(id: %q) { title }
+		b: note(id: %q) { title }
+		c: node(id: %q) { id ... on Note { title } }
+		d: nodes(ids: [%q, %q, %q]) { id }
+		e: note(id: %q) { title }
+	}`, id(1), id(2), id(3), id(1), id(4), id(42), id(42))
+
+	resp := execGraphQL(t, api, query)
+	if errs := resp["errors"]; errs != nil {
+		t.Fatalf("errores: %v", errs)
+	}
+
+	batches := repo.calls()
+	if len(batches) != 1 {
+		t.Fatalf("GetNotesBatch se llamó %d veces (%v), se esperaba 1", len(batches), batches)
+	}
+	if got := slices.Sorted(slices.Values(batches[0])); !slices.Equal(got, []int64{1, 2, 3, 4, 42}) {
+		t.Errorf("IDs del batch = %v", got)
+	}
+
+	data := resp["data"].(map[string]any)
+	if data["e"] != nil {
+		t.Errorf("nota inexistente = %v, se esperaba null", data["e"])
+	}
+	if nodes := data["d"].([]any); len(nodes) != 3 || nodes[2] != nil {
+		t.Errorf("nodes = %v, se esperaba null en la posición de la nota inexistente", nodes)
+	}
+}
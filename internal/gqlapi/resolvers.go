@@ -0,0 +1,396 @@
+package gqlapi
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/gin-gonic/gin/binding"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// Límites iguales a los de las rutas REST
+const (
+	defaultListLimit   = 20
+	defaultSearchLimit = 10
+	maxLimit           = 100
+)
+
+// noteKind es el tipo de los IDs globales de notas
+const noteKind = "Note"
+
+// rootResolver atiende Query y Mutation
+type rootResolver struct {
+	notes   db.Repository
+	sharing db.SharingRepository
+}
+
+// Query
+
+func (r *rootResolver) Node(ctx context.Context, args struct{ ID graphql.ID }) (*nodeResolver, error) {
+	note, err := r.Note(ctx, args)
+	if err != nil || note == nil {
+		return nil, err
+	}
+	return &nodeResolver{note}, nil
+}
+
+func (r *rootResolver) Nodes(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*nodeResolver, error) {
+	if len(args.IDs) > maxLimit {
+		return nil, userError(fmt.Sprintf("Máximo %d IDs", maxLimit))
+	}
+
+	ids := make([]int64, len(args.IDs))
+	for i, gid := range args.IDs {
+		id, err := unmarshalNoteID(gid)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+
+	notes, err := loaderFrom(ctx).LoadMany(ctx, ids)
+	if err != nil {
+		return nil, toError(ctx, "Error obteniendo notas", err)
+	}
+
+	out := make([]*nodeResolver, len(notes))
+	for i, note := range notes {
+		if note != nil {
+			out[i] = &nodeResolver{&noteResolver{note}}
+		}
+	}
+	return out, nil
+}
+
+func (r *rootResolver) Note(ctx context.Context, args struct{ ID graphql.ID }) (*noteResolver, error) {
+	id, err := unmarshalNoteID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := loaderFrom(ctx).Load(ctx, id)
+	if err != nil {
+		return nil, toError(ctx, "Error obteniendo nota", err)
+	}
+	if note == nil {
+		return nil, nil
+	}
+	return &noteResolver{note}, nil
+}
+
+func (r *rootResolver) Notes(ctx context.Context, args struct {
+	First *int32
+	After *string
+}) (*noteConnectionResolver, error) {
+	params := models.PaginationParams{Limit: defaultListLimit}
+	if args.First != nil {
+		if *args.First < 1 || *args.First > maxLimit {
+			return nil, userError(fmt.Sprintf("first debe estar entre 1 y %d", maxLimit))
+		}
+		params.Limit = int(*args.First)
+	}
+	if args.After != nil {
+		cursorTime, cursorID, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		params.CursorTime, params.CursorID = cursorTime, cursorID
+	}
+
+	page, err := r.notes.ListNotes(ctx, params)
+	if err != nil {
+		return nil, toError(ctx, "Error listando notas", err)
+	}
+
+	loader := loaderFrom(ctx)
+	for i := range page.Notes {
+		loader.Prime(&page.Notes[i])
+	}
+	return &noteConnectionResolver{page}, nil
+}
+
+func (r *rootResolver) SearchNotes(ctx context.Context, args struct {
+	Query string
+	First *int32
+}) ([]*noteResolver, error) {
+	if args.Query == "" {
+		return nil, userError("Se requiere query de búsqueda")
+	}
+	limit := defaultSearchLimit
+	if args.First != nil {
+		if *args.First < 1 || *args.First > maxLimit {
+			return nil, userError(fmt.Sprintf("first debe estar entre 1 y %d", maxLimit))
+		}
+		limit = int(*args.First)
+	}
+
+	notes, err := r.notes.SearchNotes(ctx, args.Query, limit)
+	if err != nil {
+		return nil, toError(ctx, "Error buscando notas", err)
+	}
+	return primeAll(ctx, notes), nil
+}
+
+func (r *rootResolver) SharedWithMe(ctx context.Context) ([]*sharedNoteResolver, error) {
+	if reqctx.UserID(ctx) == 0 {
+		return nil, unauthenticatedError()
+	}
+
+	shared, err := r.sharing.ListSharedWithMe(ctx)
+	if err != nil {
+		return nil, toError(ctx, "Error listando notas compartidas", err)
+	}
+
+	loader := loaderFrom(ctx)
+	out := make([]*sharedNoteResolver, len(shared))
+	for i := range shared {
+		loader.Prime(&shared[i].Note)
+		out[i] = &sharedNoteResolver{&shared[i]}
+	}
+	return out, nil
+}
+
+// Mutation
+
+func (r *rootResolver) CreateNote(ctx context.Context, args struct {
+	Input struct {
+		Title            string
+		Content          string
+		ClientMutationID *string
+	}
+}) (*notePayloadResolver, error) {
+	req := models.CreateNoteRequest{Title: args.Input.Title, Content: args.Input.Content}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, userError(err.Error())
+	}
+
+	note, err := r.notes.CreateNote(ctx, &req)
+	if err != nil {
+		return nil, toError(ctx, "Error creando nota", err)
+	}
+	loaderFrom(ctx).Prime(note)
+	return &notePayloadResolver{note: &noteResolver{note}, clientMutationID: args.Input.ClientMutationID}, nil
+}
+
+func (r *rootResolver) UpdateNote(ctx context.Context, args struct {
+	Input struct {
+		ID               graphql.ID
+		Title            *string
+		Content          *string
+		ClientMutationID *string
+	}
+}) (*notePayloadResolver, error) {
+	id, err := unmarshalNoteID(args.Input.ID)
+	if err != nil {
+		return nil, err
+	}
+	var req models.UpdateNoteRequest
+	if args.Input.Title != nil {
+		req.Title = *args.Input.Title
+	}
+	if args.Input.Content != nil {
+		req.Content = *args.Input.Content
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, userError(err.Error())
+	}
+
+	note, err := r.notes.UpdateNote(ctx, id, req)
+	if err != nil {
+		return nil, toError(ctx, "Error actualizando nota", err)
+	}
+	if note == nil {
+		return nil, notFoundError()
+	}
+
+	loader := loaderFrom(ctx)
+	loader.Forget(id)
+	loader.Prime(note)
+	return &notePayloadResolver{note: &noteResolver{note}, clientMutationID: args.Input.ClientMutationID}, nil
+}
+
+func (r *rootResolver) DeleteNote(ctx context.Context, args struct {
+	Input struct {
+		ID               graphql.ID
+		ClientMutationID *string
+	}
+}) (*deletePayloadResolver, error) {
+	id, err := unmarshalNoteID(args.Input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.notes.DeleteNote(ctx, id); err != nil {
+		return nil, toError(ctx, "Error eliminando nota", err)
+	}
+	loaderFrom(ctx).Forget(id)
+	return &deletePayloadResolver{deletedID: args.Input.ID, clientMutationID: args.Input.ClientMutationID}, nil
+}
+
+// Tipos
+
+type nodeResolver struct {
+	note *noteResolver
+}
+
+func (r *nodeResolver) ID() graphql.ID {
+	return r.note.ID()
+}
+
+func (r *nodeResolver) ToNote() (*noteResolver, bool) {
+	return r.note, r.note != nil
+}
+
+type noteResolver struct {
+	note *models.Note
+}
+
+func (r *noteResolver) ID() graphql.ID {
+	return relay.MarshalID(noteKind, r.note.ID)
+}
+
+func (r *noteResolver) DatabaseID() graphql.ID {
+	return graphql.ID(strconv.FormatInt(r.note.ID, 10))
+}
+
+func (r *noteResolver) OwnerID() *graphql.ID {
+	if r.note.OwnerID == nil {
+		return nil
+	}
+	id := graphql.ID(strconv.FormatInt(*r.note.OwnerID, 10))
+	return &id
+}
+
+func (r *noteResolver) Title() string           { return r.note.Title }
+func (r *noteResolver) Content() string         { return r.note.Content }
+func (r *noteResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.note.CreatedAt} }
+func (r *noteResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.note.UpdatedAt} }
+
+type noteConnectionResolver struct {
+	page *models.NotesPage
+}
+
+func (r *noteConnectionResolver) Edges() []*noteEdgeResolver {
+	edges := make([]*noteEdgeResolver, len(r.page.Notes))
+	for i := range r.page.Notes {
+		edges[i] = &noteEdgeResolver{&r.page.Notes[i]}
+	}
+	return edges
+}
+
+func (r *noteConnectionResolver) Nodes() []*noteResolver {
+	nodes := make([]*noteResolver, len(r.page.Notes))
+	for i := range r.page.Notes {
+		nodes[i] = &noteResolver{&r.page.Notes[i]}
+	}
+	return nodes
+}
+
+func (r *noteConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNextPage: r.page.NextPage}
+	if n := len(r.page.Notes); n > 0 {
+		start, end := encodeCursor(&r.page.Notes[0]), encodeCursor(&r.page.Notes[n-1])
+		info.startCursor, info.endCursor = &start, &end
+	}
+	return info
+}
+
+type noteEdgeResolver struct {
+	note *models.Note
+}
+
+func (r *noteEdgeResolver) Cursor() string      { return encodeCursor(r.note) }
+func (r *noteEdgeResolver) Node() *noteResolver { return &noteResolver{r.note} }
+
+// pageInfoResolver solo pagina hacia adelante: hasPreviousPage es siempre false,
+// como permite la especificación cuando calcularlo no es barato
+type pageInfoResolver struct {
+	hasNextPage bool
+	startCursor *string
+	endCursor   *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool     { return r.hasNextPage }
+func (r *pageInfoResolver) HasPreviousPage() bool { return false }
+func (r *pageInfoResolver) StartCursor() *string  { return r.startCursor }
+func (r *pageInfoResolver) EndCursor() *string    { return r.endCursor }
+
+type sharedNoteResolver struct {
+	shared *models.SharedNote
+}
+
+func (r *sharedNoteResolver) Role() string        { return r.shared.Role }
+func (r *sharedNoteResolver) Note() *noteResolver { return &noteResolver{&r.shared.Note} }
+
+type notePayloadResolver struct {
+	note             *noteResolver
+	clientMutationID *string
+}
+
+func (r *notePayloadResolver) Note() *noteResolver       { return r.note }
+func (r *notePayloadResolver) ClientMutationID() *string { return r.clientMutationID }
+
+type deletePayloadResolver struct {
+	deletedID        graphql.ID
+	clientMutationID *string
+}
+
+func (r *deletePayloadResolver) DeletedID() graphql.ID     { return r.deletedID }
+func (r *deletePayloadResolver) ClientMutationID() *string { return r.clientMutationID }
+
+// primeAll guarda las notas en el dataloader y arma sus resolvers
+func primeAll(ctx context.Context, notes []models.Note) []*noteResolver {
+	loader := loaderFrom(ctx)
+	out := make([]*noteResolver, len(notes))
+	for i := range notes {
+		loader.Prime(&notes[i])
+		out[i] = &noteResolver{&notes[i]}
+	}
+	return out
+}
+
+func unmarshalNoteID(id graphql.ID) (int64, error) {
+	var noteID int64
+	if relay.UnmarshalKind(id) != noteKind {
+		return 0, userError("ID inválido")
+	}
+	if err := relay.UnmarshalSpec(id, &noteID); err != nil || noteID < 1 {
+		return 0, userError("ID inválido")
+	}
+	return noteID, nil
+}
+
+// Cursores opacos: base64 de "created_at|id", el keyset de ListNotes
+func encodeCursor(note *models.Note) string {
+	raw := note.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatInt(note.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, userError("Cursor inválido")
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, 0, userError("Cursor inválido")
+	}
+	cursorTime, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, 0, userError("Cursor inválido")
+	}
+	cursorID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || cursorID < 1 {
+		return time.Time{}, 0, userError("Cursor inválido")
+	}
+	return cursorTime, cursorID, nil
+}
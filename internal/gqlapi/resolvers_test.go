@@ -0,0 +1,79 @@
+package gqlapi
+
+import (
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	zone := time.FixedZone("UTC-3", -3*60*60)
+	note := &models.Note{ID: 42, CreatedAt: time.Date(2025, 1, 2, 22, 30, 0, 123456789, zone)}
+
+	cursorTime, cursorID, err := decodeCursor(encodeCursor(note))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cursorTime.Equal(note.CreatedAt) || cursorID != 42 {
+		t.Errorf("decodeCursor = %s, %d; se esperaba %s, 42", cursorTime, cursorID, note.CreatedAt)
+	}
+}
+
+func TestDecodeMalformedCursor(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := map[string]string{
+		"vacío":                 "",
+		"no es base64":          "%%%",
+		"base64 con padding":    base64.URLEncoding.EncodeToString([]byte("2025-01-01T00:00:00Z|1")),
+		"sin separador":         encode("2025-01-01T00:00:00Z"),
+		"fecha inválida":        encode("ayer|1"),
+		"fecha sin zona":        encode("2025-01-01 00:00:00|1"),
+		"ID no numérico":        encode("2025-01-01T00:00:00Z|uno"),
+		"ID cero":               encode("2025-01-01T00:00:00Z|0"),
+		"ID negativo":           encode("2025-01-01T00:00:00Z|-5"),
+		"ID fuera de rango":     encode("2025-01-01T00:00:00Z|99999999999999999999"),
+		"separador de más":      encode("2025-01-01T00:00:00Z|1|2"),
+		"ID global en su lugar": "Tm90ZToxMA",
+	}
+
+	for name, cursor := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := decodeCursor(cursor)
+			var rerr *resolverError
+			if !errors.As(err, &rerr) || rerr.code != codeBadUserInput {
+				t.Errorf("decodeCursor(%q) = %v, se esperaba BAD_USER_INPUT", cursor, err)
+			}
+		})
+	}
+}
+
+// Un cursor inválido se informa en errors sin llegar al repositorio
+func TestNotesRejectsMalformedCursor(t *testing.T) {
+	repo := &batchRepository{}
+	api, err := New(repo, nil, Options{Logger: slog.New(slog.DiscardHandler)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := execGraphQL(t, api, `{ notes(first: 5, after: "no-es-un-cursor") { nodes { id } } }`)
+
+	errs, _ := resp["errors"].([]any)
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, se esperaba uno", resp["errors"])
+	}
+	gqlErr := errs[0].(map[string]any)
+	if gqlErr["message"] != "Cursor inválido" {
+		t.Errorf("mensaje = %v", gqlErr["message"])
+	}
+	if ext, _ := gqlErr["extensions"].(map[string]any); ext["code"] != codeBadUserInput {
+		t.Errorf("extensions = %v, se esperaba %s", gqlErr["extensions"], codeBadUserInput)
+	}
+	if repo.listed != 0 {
+		t.Error("ListNotes se llamó con un cursor inválido")
+	}
+}
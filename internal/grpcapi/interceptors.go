@@ -0,0 +1,152 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+	"github.com/ybotet/notes-api-optimization/internal/middleware"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata equivalente a los headers de la API REST
+const (
+	tenantIDKey  = "x-tenant-id"
+	userIDKey    = "x-user-id"
+	requestIDKey = "x-request-id"
+)
+
+// Options configura los interceptores del servidor gRPC
+type Options struct {
+	// DefaultTenant se usa si la llamada no envía x-tenant-id; vacío lo hace obligatorio
+	DefaultTenant string
+	Logger        *slog.Logger
+}
+
+// ServerOptions retorna los interceptores unary y stream: contexto de la llamada
+// (tenant, usuario, request ID, IP), recuperación de panics, logging y métricas
+func ServerOptions(opts Options) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+			start := time.Now()
+			ctx, err = callContext(ctx, opts.DefaultTenant)
+			if err == nil {
+				resp, err = recoverCall(ctx, opts.Logger, info.FullMethod, func() (any, error) {
+					return handler(ctx, req)
+				})
+			}
+			observe(ctx, opts.Logger, info.FullMethod, start, err)
+			return resp, err
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			start := time.Now()
+			ctx, err := callContext(ss.Context(), opts.DefaultTenant)
+			if err == nil {
+				_, err = recoverCall(ctx, opts.Logger, info.FullMethod, func() (any, error) {
+					return nil, handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+				})
+			}
+			observe(ctx, opts.Logger, info.FullMethod, start, err)
+			return err
+		}),
+	}
+}
+
+// callContext guarda en el contexto los mismos datos que los middlewares de gin
+func callContext(ctx context.Context, defaultTenant string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	requestID := middleware.RequestIDOrNew(first(requestIDKey))
+	ctx = reqctx.WithRequestID(ctx, requestID)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip := p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		ctx = reqctx.WithClientIP(ctx, ip)
+	}
+
+	if raw := first(userIDKey); raw != "" {
+		userID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || userID < 1 {
+			return ctx, status.Error(codes.Unauthenticated, "Usuario inválido")
+		}
+		ctx = reqctx.WithUserID(ctx, userID)
+	}
+
+	tenantID := first(tenantIDKey)
+	if tenantID == "" {
+		tenantID = defaultTenant
+	}
+	if !middleware.ValidTenantID(tenantID) {
+		return ctx, status.Error(codes.InvalidArgument, "Tenant inválido")
+	}
+	return reqctx.WithTenantID(ctx, tenantID), nil
+}
+
+// recoverCall convierte un panic del handler en codes.Internal
+func recoverCall(ctx context.Context, logger *slog.Logger, method string, call func() (any, error)) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorContext(ctx, "panic en handler gRPC", slog.Any("panic", r), slog.String("method", method))
+			err = status.Error(codes.Internal, "Error interno")
+		}
+	}()
+	return call()
+}
+
+// observe registra la llamada como una línea estructurada y en las métricas gRPC
+func observe(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	latency := time.Since(start)
+
+	metrics.GRPCRequests.WithLabelValues(method, code.String()).Inc()
+	metrics.GRPCDuration.WithLabelValues(method, code.String()).Observe(latency.Seconds())
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", latency),
+		slog.String("client_ip", reqctx.ClientIP(ctx)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	logger.LogAttrs(ctx, level, "llamada gRPC", attrs...)
+}
+
+// contextStream reemplaza el contexto del stream por el enriquecido por callContext
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
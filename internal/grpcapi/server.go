@@ -0,0 +1,234 @@
+// Package grpcapi expone el servicio de notas por gRPC (proto/notes/v1) sobre el
+// mismo db.Repository que las rutas REST.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/pb"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Límites iguales a los de las rutas REST
+const (
+	defaultListLimit   = 20
+	defaultSearchLimit = 10
+	maxLimit           = 100
+)
+
+// Server implementa pb.NotesServiceServer
+type Server struct {
+	pb.UnimplementedNotesServiceServer
+	repo db.Repository
+}
+
+func NewServer(repo db.Repository) *Server {
+	return &Server{repo: repo}
+}
+
+func (s *Server) CreateNote(ctx context.Context, req *pb.CreateNoteRequest) (*pb.Note, error) {
+	in := req.ToModel()
+	if err := validate(&in); err != nil {
+		return nil, err
+	}
+
+	note, err := s.repo.CreateNote(ctx, &in)
+	if err != nil {
+		return nil, toStatus(ctx, "Error creando nota", err)
+	}
+	return pb.NoteFromModel(note), nil
+}
+
+func (s *Server) GetNote(ctx context.Context, req *pb.GetNoteRequest) (*pb.Note, error) {
+	if req.GetId() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "ID inválido")
+	}
+
+	note, err := s.repo.GetNoteByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(ctx, "Error obteniendo nota", err)
+	}
+	if note == nil {
+		return nil, status.Error(codes.NotFound, "Nota no encontrada")
+	}
+	return pb.NoteFromModel(note), nil
+}
+
+func (s *Server) BatchGetNotes(ctx context.Context, req *pb.BatchGetNotesRequest) (*pb.NoteList, error) {
+	if len(req.GetIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Se requieren IDs")
+	}
+
+	notes, err := s.repo.GetNotesBatch(ctx, req.GetIds())
+	if err != nil {
+		return nil, toStatus(ctx, "Error obteniendo notas", err)
+	}
+	return pb.NoteListFromModels(notes), nil
+}
+
+func (s *Server) ListNotes(ctx context.Context, req *pb.ListNotesRequest) (*pb.NotesPage, error) {
+	params, err := paginationParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.ListNotes(ctx, params)
+	if err != nil {
+		return nil, toStatus(ctx, "Error listando notas", err)
+	}
+	return pb.NotesPageFromModel(page), nil
+}
+
+// StreamNotes pagina con keyset desde el cursor del request y envía cada nota;
+// limit es el tamaño de cada página leída de la base
+func (s *Server) StreamNotes(req *pb.ListNotesRequest, stream grpc.ServerStreamingServer[pb.Note]) error {
+	ctx := stream.Context()
+	params, err := paginationParams(req)
+	if err != nil {
+		return err
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultListLimit
+	}
+
+	for {
+		page, err := s.repo.ListNotes(ctx, params)
+		if err != nil {
+			return toStatus(ctx, "Error listando notas", err)
+		}
+
+		for i := range page.Notes {
+			if err := stream.Send(pb.NoteFromModel(&page.Notes[i])); err != nil {
+				return err
+			}
+		}
+
+		if !page.NextPage || len(page.Notes) == 0 {
+			return nil
+		}
+		last := page.Notes[len(page.Notes)-1]
+		params.CursorTime, params.CursorID = last.CreatedAt, last.ID
+	}
+}
+
+func (s *Server) SearchNotes(ctx context.Context, req *pb.SearchNotesRequest) (*pb.NoteList, error) {
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Se requiere query de búsqueda")
+	}
+
+	// Igual que REST: un límite fuera de rango usa el valor por defecto
+	limit := int(req.GetLimit())
+	if limit < 1 || limit > maxLimit {
+		limit = defaultSearchLimit
+	}
+
+	notes, err := s.repo.SearchNotes(ctx, req.GetQuery(), limit)
+	if err != nil {
+		return nil, toStatus(ctx, "Error buscando notas", err)
+	}
+	return pb.NoteListFromModels(notes), nil
+}
+
+func (s *Server) UpdateNote(ctx context.Context, req *pb.UpdateNoteRequest) (*pb.Note, error) {
+	if req.GetId() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "ID inválido")
+	}
+	in := req.ToModel()
+	if err := validate(&in); err != nil {
+		return nil, err
+	}
+
+	note, err := s.repo.UpdateNote(ctx, req.GetId(), in)
+	if err != nil {
+		return nil, toStatus(ctx, "Error actualizando nota", err)
+	}
+	if note == nil {
+		return nil, status.Error(codes.NotFound, "Nota no encontrada")
+	}
+	return pb.NoteFromModel(note), nil
+}
+
+func (s *Server) DeleteNote(ctx context.Context, req *pb.DeleteNoteRequest) (*emptypb.Empty, error) {
+	if req.GetId() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "ID inválido")
+	}
+
+	if err := s.repo.DeleteNote(ctx, req.GetId()); err != nil {
+		return nil, toStatus(ctx, "Error eliminando nota", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) GetStats(ctx context.Context, _ *pb.GetStatsRequest) (*structpb.Struct, error) {
+	stats, err := s.repo.GetStats(ctx)
+	if err != nil {
+		return nil, toStatus(ctx, "Error obteniendo estadísticas", err)
+	}
+
+	// Las estadísticas incluyen structs (pool, réplicas): se normalizan como en la respuesta JSON
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return nil, toStatus(ctx, "Error obteniendo estadísticas", err)
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, toStatus(ctx, "Error obteniendo estadísticas", err)
+	}
+
+	out, err := structpb.NewStruct(normalized)
+	if err != nil {
+		return nil, toStatus(ctx, "Error obteniendo estadísticas", err)
+	}
+	return out, nil
+}
+
+func paginationParams(req *pb.ListNotesRequest) (models.PaginationParams, error) {
+	params := models.PaginationParams{
+		Limit:    int(req.GetLimit()),
+		CursorID: req.GetCursorId(),
+	}
+	if req.GetCursorTime() != nil {
+		params.CursorTime = req.GetCursorTime().AsTime()
+	}
+	if err := validate(&params); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// validate aplica las mismas reglas de binding que las rutas REST
+func validate(obj any) error {
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// toStatus traduce los errores del repositorio a códigos gRPC. Los errores internos
+// se registran y el cliente recibe un mensaje genérico, igual que en REST.
+func toStatus(ctx context.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, db.ErrNoteNotFound):
+		return status.Error(codes.NotFound, "Nota no encontrada")
+	case errors.Is(err, db.ErrMissingTenant):
+		return status.Error(codes.InvalidArgument, "Tenant no definido")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	slog.ErrorContext(ctx, message, "error", err)
+	return status.Error(codes.Internal, message)
+}
@@ -0,0 +1,297 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/pb"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeRepository guarda notas en memoria ordenadas por (created_at DESC, id DESC),
+// registra el tenant, el usuario y los parámetros de cada llamada y devuelve err si está definido
+type fakeRepository struct {
+	db.Repository
+	notes []models.Note
+	err   error
+
+	mu      sync.Mutex
+	tenants []string
+	users   []int64
+	pages   []models.PaginationParams
+}
+
+func (r *fakeRepository) record(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants = append(r.tenants, reqctx.TenantID(ctx))
+	r.users = append(r.users, reqctx.UserID(ctx))
+}
+
+func (r *fakeRepository) GetNoteByID(ctx context.Context, id int64) (*models.Note, error) {
+	r.record(ctx)
+	if r.err != nil {
+		return nil, r.err
+	}
+	for i := range r.notes {
+		if r.notes[i].ID == id {
+			return &r.notes[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) DeleteNote(ctx context.Context, _ int64) error {
+	r.record(ctx)
+	return r.err
+}
+
+func (r *fakeRepository) ListNotes(ctx context.Context, params models.PaginationParams) (*models.NotesPage, error) {
+	r.record(ctx)
+	r.mu.Lock()
+	r.pages = append(r.pages, params)
+	r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	page := &models.NotesPage{Notes: []models.Note{}}
+	for _, n := range r.notes {
+		if !params.CursorTime.IsZero() && !(n.CreatedAt.Before(params.CursorTime) ||
+			n.CreatedAt.Equal(params.CursorTime) && n.ID < params.CursorID) {
+			continue
+		}
+		if len(page.Notes) == params.Limit {
+			page.NextPage = true
+			break
+		}
+		page.Notes = append(page.Notes, n)
+	}
+	return page, nil
+}
+
+// newTestClient levanta el servidor sobre bufconn con los mismos interceptores que main
+func newTestClient(t *testing.T, repo db.Repository, defaultTenant string) pb.NotesServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(ServerOptions(Options{
+		DefaultTenant: defaultTenant,
+		Logger:        slog.New(slog.DiscardHandler),
+	})...)
+	pb.RegisterNotesServiceServer(server, NewServer(repo))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewNotesServiceClient(conn)
+}
+
+func newNotes(n int) []models.Note {
+	// Dos notas por segundo para que el cursor desempate por ID
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	notes := make([]models.Note, n)
+	for i := range notes {
+		id := int64(n - i)
+		notes[i] = models.Note{ID: id, Title: "nota", CreatedAt: base.Add(time.Duration(id/2) * time.Second)}
+	}
+	return notes
+}
+
+func TestRepositoryErrorsToStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{"nota inexistente", db.ErrNoteNotFound, codes.NotFound, "Nota no encontrada"},
+		{"sin tenant", db.ErrMissingTenant, codes.InvalidArgument, "Tenant no definido"},
+		{"timeout", context.DeadlineExceeded, codes.DeadlineExceeded, ""},
+		{"cancelación", context.Canceled, codes.Canceled, ""},
+		// Los errores internos no se filtran al cliente
+		{"error interno", errors.New("conexión rechazada por 10.0.0.5"), codes.Internal, "Error eliminando nota"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, &fakeRepository{err: tt.err}, "default")
+
+			_, err := client.DeleteNote(t.Context(), &pb.DeleteNoteRequest{Id: 1})
+			st := status.Convert(err)
+			if st.Code() != tt.code {
+				t.Errorf("código = %s, se esperaba %s (%v)", st.Code(), tt.code, err)
+			}
+			if tt.message != "" && st.Message() != tt.message {
+				t.Errorf("mensaje = %q, se esperaba %q", st.Message(), tt.message)
+			}
+		})
+	}
+}
+
+func TestGetNoteStatus(t *testing.T) {
+	client := newTestClient(t, &fakeRepository{notes: newNotes(1)}, "default")
+
+	if _, err := client.GetNote(t.Context(), &pb.GetNoteRequest{Id: 0}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ID 0: código = %s, se esperaba InvalidArgument", status.Code(err))
+	}
+	if _, err := client.GetNote(t.Context(), &pb.GetNoteRequest{Id: 99}); status.Code(err) != codes.NotFound {
+		t.Errorf("nota nil: código = %s, se esperaba NotFound", status.Code(err))
+	}
+	if note, err := client.GetNote(t.Context(), &pb.GetNoteRequest{Id: 1}); err != nil || note.GetId() != 1 {
+		t.Errorf("GetNote = %v, %v", note, err)
+	}
+}
+
+func TestCallMetadata(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultTenant string
+		md            []string
+		code          codes.Code
+		tenant        string
+		user          int64
+	}{
+		{"tenant y usuario", "default", []string{"x-tenant-id", "acme", "x-user-id", "42"}, codes.OK, "acme", 42},
+		{"tenant por defecto", "default", nil, codes.OK, "default", 0},
+		{"tenant obligatorio", "", nil, codes.InvalidArgument, "", 0},
+		{"tenant inválido", "default", []string{"x-tenant-id", "acme/../otro"}, codes.InvalidArgument, "", 0},
+		{"usuario no numérico", "default", []string{"x-user-id", "admin"}, codes.Unauthenticated, "", 0},
+		{"usuario cero", "default", []string{"x-user-id", "0"}, codes.Unauthenticated, "", 0},
+		{"usuario negativo", "default", []string{"x-user-id", "-1"}, codes.Unauthenticated, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			client := newTestClient(t, repo, tt.defaultTenant)
+
+			ctx := metadata.AppendToOutgoingContext(t.Context(), tt.md...)
+			var header metadata.MD
+			_, err := client.DeleteNote(ctx, &pb.DeleteNoteRequest{Id: 1}, grpc.Header(&header))
+			if status.Code(err) != tt.code {
+				t.Fatalf("código = %s, se esperaba %s (%v)", status.Code(err), tt.code, err)
+			}
+			if len(header.Get("x-request-id")) == 0 {
+				t.Error("la respuesta no incluye x-request-id")
+			}
+
+			if tt.code != codes.OK {
+				if len(repo.tenants) != 0 {
+					t.Error("la llamada llegó al repositorio")
+				}
+				return
+			}
+			if len(repo.tenants) != 1 || repo.tenants[0] != tt.tenant || repo.users[0] != tt.user {
+				t.Errorf("contexto en el repositorio: tenants %v, usuarios %v", repo.tenants, repo.users)
+			}
+		})
+	}
+}
+
+func TestStreamNotesPaging(t *testing.T) {
+	repo := &fakeRepository{notes: newNotes(7)}
+	client := newTestClient(t, repo, "default")
+
+	ctx := metadata.AppendToOutgoingContext(t.Context(), "x-tenant-id", "acme")
+	stream, err := client.StreamNotes(ctx, &pb.ListNotesRequest{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for {
+		note, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		ids = append(ids, note.GetId())
+	}
+
+	want := []int64{7, 6, 5, 4, 3, 2, 1}
+	if len(ids) != len(want) {
+		t.Fatalf("ids = %v, se esperaba %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("ids = %v, se esperaba %v", ids, want)
+		}
+	}
+
+	// Tres páginas de 3, cada una desde la última nota de la anterior
+	if len(repo.pages) != 3 {
+		t.Fatalf("páginas leídas = %d, se esperaba 3", len(repo.pages))
+	}
+	for i, wantCursor := range []int64{0, 5, 2} {
+		if p := repo.pages[i]; p.Limit != 3 || p.CursorID != wantCursor {
+			t.Errorf("página %d: limit %d, cursor %d; se esperaba 3, %d", i, p.Limit, p.CursorID, wantCursor)
+		}
+	}
+	// El stream recibe el contexto de los interceptores
+	for _, tenant := range repo.tenants {
+		if tenant != "acme" {
+			t.Errorf("tenant en el repositorio = %q, se esperaba acme", tenant)
+		}
+	}
+}
+
+func TestStreamNotesDefaultLimitAndErrors(t *testing.T) {
+	repo := &fakeRepository{notes: newNotes(25)}
+	client := newTestClient(t, repo, "default")
+
+	stream, err := client.StreamNotes(t.Context(), &pb.ListNotesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for ; ; n++ {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+	}
+	if n != 25 || len(repo.pages) != 2 || repo.pages[0].Limit != defaultListLimit {
+		t.Errorf("notas = %d, páginas = %d (%+v)", n, len(repo.pages), repo.pages)
+	}
+
+	// Límite fuera de rango y errores del repositorio
+	stream, _ = client.StreamNotes(t.Context(), &pb.ListNotesRequest{Limit: maxLimit + 1})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("limit %d: código = %s, se esperaba InvalidArgument", maxLimit+1, status.Code(err))
+	}
+
+	client = newTestClient(t, &fakeRepository{err: db.ErrMissingTenant}, "default")
+	stream, _ = client.StreamNotes(t.Context(), &pb.ListNotesRequest{})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("error del repositorio: código = %s, se esperaba InvalidArgument", status.Code(err))
+	}
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ybotet/notes-api-optimization/internal/advisor"
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	slowQueries db.SlowQueryRepository
+	diagnostics db.DiagnosticsRepository
+	explainer   db.ExplainRepository
+	advisor     *advisor.Advisor
+}
+
+func NewAdminHandler(slowQueries db.SlowQueryRepository, diagnostics db.DiagnosticsRepository, explainer db.ExplainRepository, indexAdvisor *advisor.Advisor) *AdminHandler {
+	return &AdminHandler{slowQueries: slowQueries, diagnostics: diagnostics, explainer: explainer, advisor: indexAdvisor}
+}
+
+// ListSlowQueries lista las últimas consultas lentas capturadas
+func (h *AdminHandler) ListSlowQueries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	queries, err := h.slowQueries.ListSlowQueries(c.Request.Context(), limit)
+	if err != nil {
+		serverError(c, "Error listando consultas lentas", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, queries)
+}
+
+// GetSlowQuery obtiene una consulta lenta con su plan EXPLAIN (FORMAT JSON)
+func (h *AdminHandler) GetSlowQuery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	query, err := h.slowQueries.GetSlowQuery(c.Request.Context(), id)
+	if err != nil {
+		serverError(c, "Error obteniendo consulta lenta", err)
+		return
+	}
+
+	if query == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Consulta lenta no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, query)
+}
+
+// IndexUsage lista los índices con su uso, tamaño y bloat estimado
+func (h *AdminHandler) IndexUsage(c *gin.Context) {
+	indexes, err := h.diagnostics.IndexUsage(c.Request.Context())
+	if err != nil {
+		serverError(c, "Error consultando índices", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, indexes)
+}
+
+// TableSizes lista el tamaño de tablas e índices
+func (h *AdminHandler) TableSizes(c *gin.Context) {
+	tables, err := h.diagnostics.TableSizes(c.Request.Context())
+	if err != nil {
+		serverError(c, "Error consultando tablas", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tables)
+}
+
+// TopStatements lista las sentencias más costosas de pg_stat_statements
+func (h *AdminHandler) TopStatements(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	orderBy := c.DefaultQuery("order_by", "total")
+	switch orderBy {
+	case "total", "mean", "max", "calls", "rows":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order_by debe ser total, mean, max, calls o rows"})
+		return
+	}
+
+	statements, err := h.diagnostics.TopStatements(c.Request.Context(), orderBy, limit)
+	if err != nil {
+		if errors.Is(err, db.ErrStatementsUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "pg_stat_statements no habilitado"})
+			return
+		}
+		serverError(c, "Error consultando sentencias", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statements)
+}
+
+// ResetStatements reinicia las estadísticas de pg_stat_statements
+func (h *AdminHandler) ResetStatements(c *gin.Context) {
+	if err := h.diagnostics.ResetStatements(c.Request.Context()); err != nil {
+		if errors.Is(err, db.ErrStatementsUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "pg_stat_statements no habilitado"})
+			return
+		}
+		serverError(c, "Error reiniciando estadísticas", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Sessions lista las sesiones activas (?blocked=true solo las bloqueadas)
+func (h *AdminHandler) Sessions(c *gin.Context) {
+	blockedOnly := c.Query("blocked") == "true"
+
+	sessions, err := h.diagnostics.Sessions(c.Request.Context(), blockedOnly)
+	if err != nil {
+		serverError(c, "Error consultando sesiones", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// CacheHitRatio retorna la proporción de aciertos de cache de la base y por tabla
+func (h *AdminHandler) CacheHitRatio(c *gin.Context) {
+	ratio, err := h.diagnostics.CacheHitRatio(c.Request.Context())
+	if err != nil {
+		serverError(c, "Error consultando cache", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ratio)
+}
+
+// ExplainQuery ejecuta EXPLAIN ANALYZE sobre una consulta del repositorio y resume su plan
+func (h *AdminHandler) ExplainQuery(c *gin.Context) {
+	var req models.ExplainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.explainer.ExplainQuery(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidExplain) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		serverError(c, "Error analizando consulta", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// IndexAdvisor revisa índices y seq scans y sugiere DDL (?format=sql devuelve solo el script)
+func (h *AdminHandler) IndexAdvisor(c *gin.Context) {
+	report, err := h.advisor.Report(c.Request.Context())
+	if err != nil {
+		serverError(c, "Error generando reporte de índices", err)
+		return
+	}
+
+	if c.Query("format") == "sql" {
+		c.String(http.StatusOK, advisor.Script(report))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
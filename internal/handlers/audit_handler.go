@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	repo db.AuditRepository
+}
+
+func NewAuditHandler(repo db.AuditRepository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+// ListAuditEntries lista el log de auditoría con paginación por keyset
+func (h *AuditHandler) ListAuditEntries(c *gin.Context) {
+	var params models.AuditParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.repo.ListAuditEntries(c.Request.Context(), params)
+	if err != nil {
+		serverError(c, "Error listando auditoría", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
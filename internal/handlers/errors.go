@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serverError registra el error real y responde 500 con un mensaje genérico
+func serverError(c *gin.Context, message string, err error) {
+	slog.ErrorContext(c.Request.Context(), message,
+		"error", err,
+		"route", c.FullPath())
+	respond(c, http.StatusInternalServerError, gin.H{"error": message})
+}
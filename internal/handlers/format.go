@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/pb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// offeredFormats en orden de preferencia: sin Accept (o con */*) se responde JSON.
+// MessagePack y Protobuf son los formatos compactos para los servicios internos.
+var offeredFormats = []string{binding.MIMEJSON, binding.MIMEMSGPACK2, binding.MIMEMSGPACK, binding.MIMEPROTOBUF}
+
+// respond escribe obj en el formato negociado con Accept. Protobuf solo cubre los
+// tipos definidos en proto/notes/v1/notes.proto (notas, comparticiones y enlaces);
+// el resto (p. ej. /stats) sale en JSON.
+func respond(c *gin.Context, status int, obj any) {
+	switch c.NegotiateFormat(offeredFormats...) {
+	case binding.MIMEMSGPACK2, binding.MIMEMSGPACK:
+		data, err := marshalMsgPack(obj)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error serializando respuesta"})
+			return
+		}
+		c.Data(status, binding.MIMEMSGPACK2, data)
+
+	case binding.MIMEPROTOBUF:
+		if msg, ok := toProto(obj); ok {
+			c.ProtoBuf(status, msg)
+			return
+		}
+		c.JSON(status, obj)
+
+	default:
+		c.JSON(status, obj)
+	}
+}
+
+// bind decodifica el cuerpo según Content-Type (JSON si no es MessagePack ni
+// Protobuf) y aplica las validaciones de binding de los modelos
+func bind(c *gin.Context, obj any) error {
+	switch c.ContentType() {
+	case binding.MIMEMSGPACK2, binding.MIMEMSGPACK:
+		dec := msgpack.NewDecoder(c.Request.Body)
+		dec.SetCustomStructTag("json")
+		if err := dec.Decode(obj); err != nil {
+			return fmt.Errorf("error decodificando MessagePack: %w", err)
+		}
+
+	case binding.MIMEPROTOBUF:
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return fmt.Errorf("error leyendo cuerpo: %w", err)
+		}
+		if err := unmarshalProto(body, obj); err != nil {
+			return err
+		}
+
+	default:
+		return c.ShouldBindJSON(obj)
+	}
+
+	return binding.Validator.ValidateStruct(obj)
+}
+
+func marshalMsgPack(obj any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	// Mismos nombres de campo que en JSON
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(obj); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toProto convierte las respuestas de las rutas de notas a su mensaje Protobuf
+func toProto(obj any) (proto.Message, bool) {
+	switch v := obj.(type) {
+	case *models.Note:
+		return pb.NoteFromModel(v), true
+	case []models.Note:
+		return pb.NoteListFromModels(v), true
+	case *models.NotesPage:
+		return pb.NotesPageFromModel(v), true
+	case *models.NoteShare:
+		return pb.NoteShareFromModel(v), true
+	case []models.NoteShare:
+		return pb.NoteShareListFromModels(v), true
+	case []models.SharedNote:
+		return pb.SharedNoteListFromModels(v), true
+	case *models.PublicLink:
+		return pb.PublicLinkFromModel(v), true
+	case []models.PublicLink:
+		return pb.PublicLinkListFromModels(v), true
+	case gin.H:
+		if msg, ok := v["error"].(string); ok && len(v) == 1 {
+			return &pb.Error{Error: msg}, true
+		}
+	}
+	return nil, false
+}
+
+func unmarshalProto(body []byte, obj any) error {
+	switch req := obj.(type) {
+	case *models.CreateNoteRequest:
+		var msg pb.CreateNoteRequest
+		if err := proto.Unmarshal(body, &msg); err != nil {
+			return fmt.Errorf("error decodificando Protobuf: %w", err)
+		}
+		*req = msg.ToModel()
+	case *models.UpdateNoteRequest:
+		var msg pb.UpdateNoteRequest
+		if err := proto.Unmarshal(body, &msg); err != nil {
+			return fmt.Errorf("error decodificando Protobuf: %w", err)
+		}
+		*req = msg.ToModel()
+	case *models.ShareNoteRequest:
+		var msg pb.ShareNoteRequest
+		if err := proto.Unmarshal(body, &msg); err != nil {
+			return fmt.Errorf("error decodificando Protobuf: %w", err)
+		}
+		*req = msg.ToModel()
+	case *models.CreatePublicLinkRequest:
+		var msg pb.CreatePublicLinkRequest
+		if err := proto.Unmarshal(body, &msg); err != nil {
+			return fmt.Errorf("error decodificando Protobuf: %w", err)
+		}
+		*req = msg.ToModel()
+	default:
+		return fmt.Errorf("%T no tiene mensaje Protobuf", obj)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -21,18 +22,18 @@ func NewNoteHandler(repo db.Repository) *NoteHandler {
 // CreateNote crea una nueva nota
 func (h *NoteHandler) CreateNote(c *gin.Context) {
 	var req models.CreateNoteRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bind(c, &req); err != nil {
+		respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	note, err := h.repo.CreateNote(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creando nota"})
+		serverError(c, "Error creando nota", err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, note)
+	respond(c, http.StatusCreated, note)
 }
 
 // GetNote obtiene una nota por ID
@@ -40,29 +41,29 @@ func (h *NoteHandler) GetNote(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		respond(c, http.StatusBadRequest, gin.H{"error": "ID inválido"})
 		return
 	}
 
 	note, err := h.repo.GetNoteByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo nota"})
+		serverError(c, "Error obteniendo nota", err)
 		return
 	}
 
 	if note == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
+		respond(c, http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
 		return
 	}
 
-	c.JSON(http.StatusOK, note)
+	respond(c, http.StatusOK, note)
 }
 
 // GetNotesBatch obtiene múltiples notas en batch
 func (h *NoteHandler) GetNotesBatch(c *gin.Context) {
 	idsParam := c.QueryArray("ids")
 	if len(idsParam) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requieren IDs"})
+		respond(c, http.StatusBadRequest, gin.H{"error": "Se requieren IDs"})
 		return
 	}
 
@@ -70,7 +71,7 @@ func (h *NoteHandler) GetNotesBatch(c *gin.Context) {
 	for _, idStr := range idsParam {
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+			respond(c, http.StatusBadRequest, gin.H{"error": "ID inválido"})
 			return
 		}
 		ids = append(ids, id)
@@ -78,35 +79,35 @@ func (h *NoteHandler) GetNotesBatch(c *gin.Context) {
 
 	notes, err := h.repo.GetNotesBatch(c.Request.Context(), ids)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo notas"})
+		serverError(c, "Error obteniendo notas", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, notes)
+	respond(c, http.StatusOK, notes)
 }
 
 // ListNotes lista notas con paginación
 func (h *NoteHandler) ListNotes(c *gin.Context) {
 	var params models.PaginationParams
 	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	page, err := h.repo.ListNotes(c.Request.Context(), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listando notas"})
+		serverError(c, "Error listando notas", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, page)
+	respond(c, http.StatusOK, page)
 }
 
 // SearchNotes busca notas por título
 func (h *NoteHandler) SearchNotes(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Se requiere query de búsqueda"})
+		respond(c, http.StatusBadRequest, gin.H{"error": "Se requiere query de búsqueda"})
 		return
 	}
 
@@ -118,11 +119,11 @@ func (h *NoteHandler) SearchNotes(c *gin.Context) {
 
 	notes, err := h.repo.SearchNotes(c.Request.Context(), query, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error buscando notas"})
+		serverError(c, "Error buscando notas", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, notes)
+	respond(c, http.StatusOK, notes)
 }
 
 // UpdateNote actualiza una nota
@@ -130,28 +131,28 @@ func (h *NoteHandler) UpdateNote(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		respond(c, http.StatusBadRequest, gin.H{"error": "ID inválido"})
 		return
 	}
 
 	var req models.UpdateNoteRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := bind(c, &req); err != nil {
+		respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	note, err := h.repo.UpdateNote(c.Request.Context(), id, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error actualizando nota"})
+		serverError(c, "Error actualizando nota", err)
 		return
 	}
 
 	if note == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
+		respond(c, http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
 		return
 	}
 
-	c.JSON(http.StatusOK, note)
+	respond(c, http.StatusOK, note)
 }
 
 // DeleteNote elimina una nota
@@ -159,16 +160,16 @@ func (h *NoteHandler) DeleteNote(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		respond(c, http.StatusBadRequest, gin.H{"error": "ID inválido"})
 		return
 	}
 
 	if err := h.repo.DeleteNote(c.Request.Context(), id); err != nil {
-		if err.Error() == "nota no encontrada" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
+		if errors.Is(err, db.ErrNoteNotFound) {
+			respond(c, http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error eliminando nota"})
+		serverError(c, "Error eliminando nota", err)
 		return
 	}
 
@@ -179,9 +180,9 @@ func (h *NoteHandler) DeleteNote(c *gin.Context) {
 func (h *NoteHandler) GetStats(c *gin.Context) {
 	stats, err := h.repo.GetStats(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error obteniendo estadísticas"})
+		serverError(c, "Error obteniendo estadísticas", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	respond(c, http.StatusOK, stats)
 }
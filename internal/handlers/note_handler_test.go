@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/pb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+var noteCreatedAt = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func testNote(id int64, title string) models.Note {
+	return models.Note{ID: id, Title: title, Content: "contenido", CreatedAt: noteCreatedAt, UpdatedAt: noteCreatedAt}
+}
+
+// fakeNoteRepository devuelve notas fijas y registra lo que recibe
+type fakeNoteRepository struct {
+	db.Repository
+	created *models.CreateNoteRequest
+	updated *models.UpdateNoteRequest
+	batch   []int64
+}
+
+func (r *fakeNoteRepository) CreateNote(_ context.Context, req *models.CreateNoteRequest) (*models.Note, error) {
+	r.created = req
+	note := testNote(1, req.Title)
+	return &note, nil
+}
+
+func (r *fakeNoteRepository) GetNoteByID(_ context.Context, id int64) (*models.Note, error) {
+	if id != 1 {
+		return nil, nil
+	}
+	note := testNote(1, "primera")
+	return &note, nil
+}
+
+func (r *fakeNoteRepository) GetNotesBatch(_ context.Context, ids []int64) ([]models.Note, error) {
+	r.batch = ids
+	notes := []models.Note{}
+	for _, id := range ids {
+		notes = append(notes, testNote(id, "batch"))
+	}
+	return notes, nil
+}
+
+func (r *fakeNoteRepository) ListNotes(context.Context, models.PaginationParams) (*models.NotesPage, error) {
+	return &models.NotesPage{Notes: []models.Note{testNote(2, "segunda"), testNote(1, "primera")}, NextPage: true}, nil
+}
+
+func (r *fakeNoteRepository) SearchNotes(_ context.Context, query string, _ int) ([]models.Note, error) {
+	return []models.Note{testNote(3, query)}, nil
+}
+
+func (r *fakeNoteRepository) UpdateNote(_ context.Context, id int64, req models.UpdateNoteRequest) (*models.Note, error) {
+	r.updated = &req
+	note := testNote(id, req.Title)
+	return &note, nil
+}
+
+func (r *fakeNoteRepository) GetStats(context.Context) (map[string]interface{}, error) {
+	return map[string]interface{}{"total_notes": 3}, nil
+}
+
+func newNoteRouter(repo db.Repository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewNoteHandler(repo)
+
+	router := gin.New()
+	router.POST("/notes", h.CreateNote)
+	router.GET("/notes", h.ListNotes)
+	router.GET("/notes/batch", h.GetNotesBatch)
+	router.GET("/notes/search", h.SearchNotes)
+	router.GET("/notes/:id", h.GetNote)
+	router.PUT("/notes/:id", h.UpdateNote)
+	router.GET("/stats", h.GetStats)
+	return router
+}
+
+func serveNote(router *gin.Engine, method, target, contentType, accept string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestNoteHandlersProtobuf(t *testing.T) {
+	repo := &fakeNoteRepository{}
+	router := newNoteRouter(repo)
+
+	decode := func(t *testing.T, w *httptest.ResponseRecorder, status int, msg proto.Message) {
+		t.Helper()
+		if w.Code != status {
+			t.Fatalf("status = %d, se esperaba %d: %s", w.Code, status, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != binding.MIMEPROTOBUF {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		if err := proto.Unmarshal(w.Body.Bytes(), msg); err != nil {
+			t.Fatalf("respuesta Protobuf inválida: %v", err)
+		}
+	}
+
+	t.Run("CreateNote", func(t *testing.T) {
+		body, _ := proto.Marshal(&pb.CreateNoteRequest{Title: "nueva", Content: "desde protobuf"})
+		var note pb.Note
+		decode(t, serveNote(router, http.MethodPost, "/notes", binding.MIMEPROTOBUF, binding.MIMEPROTOBUF, body), http.StatusCreated, &note)
+		if repo.created == nil || repo.created.Title != "nueva" || repo.created.Content != "desde protobuf" {
+			t.Errorf("petición decodificada = %+v", repo.created)
+		}
+		if note.GetId() != 1 || note.GetTitle() != "nueva" || !note.GetCreatedAt().AsTime().Equal(noteCreatedAt) {
+			t.Errorf("nota = %v", &note)
+		}
+	})
+
+	t.Run("GetNote", func(t *testing.T) {
+		var note pb.Note
+		decode(t, serveNote(router, http.MethodGet, "/notes/1", "", binding.MIMEPROTOBUF, nil), http.StatusOK, &note)
+		if note.GetId() != 1 || note.GetTitle() != "primera" {
+			t.Errorf("nota = %v", &note)
+		}
+
+		var msg pb.Error
+		decode(t, serveNote(router, http.MethodGet, "/notes/9", "", binding.MIMEPROTOBUF, nil), http.StatusNotFound, &msg)
+		if msg.GetError() != "Nota no encontrada" {
+			t.Errorf("error = %v", &msg)
+		}
+	})
+
+	t.Run("ListNotes", func(t *testing.T) {
+		var page pb.NotesPage
+		decode(t, serveNote(router, http.MethodGet, "/notes?limit=2", "", binding.MIMEPROTOBUF, nil), http.StatusOK, &page)
+		if len(page.GetNotes()) != 2 || !page.GetNextPage() || page.GetNextCursorId() != 1 {
+			t.Errorf("página = %v", &page)
+		}
+	})
+
+	t.Run("SearchNotes", func(t *testing.T) {
+		var list pb.NoteList
+		decode(t, serveNote(router, http.MethodGet, "/notes/search?q=buscada", "", binding.MIMEPROTOBUF, nil), http.StatusOK, &list)
+		if len(list.GetNotes()) != 1 || list.GetNotes()[0].GetTitle() != "buscada" {
+			t.Errorf("resultado = %v", &list)
+		}
+	})
+
+	t.Run("GetNotesBatch", func(t *testing.T) {
+		var list pb.NoteList
+		decode(t, serveNote(router, http.MethodGet, "/notes/batch?ids=4&ids=5", "", binding.MIMEPROTOBUF, nil), http.StatusOK, &list)
+		if len(list.GetNotes()) != 2 || list.GetNotes()[1].GetId() != 5 {
+			t.Errorf("batch = %v", &list)
+		}
+	})
+
+	t.Run("UpdateNote", func(t *testing.T) {
+		body, _ := proto.Marshal(&pb.UpdateNoteRequest{Title: "editada"})
+		var note pb.Note
+		decode(t, serveNote(router, http.MethodPut, "/notes/1", binding.MIMEPROTOBUF, binding.MIMEPROTOBUF, body), http.StatusOK, &note)
+		if repo.updated == nil || repo.updated.Title != "editada" || note.GetTitle() != "editada" {
+			t.Errorf("petición %+v, respuesta %v", repo.updated, &note)
+		}
+	})
+
+	t.Run("cuerpo inválido", func(t *testing.T) {
+		// Las validaciones de binding aplican también al cuerpo Protobuf
+		body, _ := proto.Marshal(&pb.CreateNoteRequest{Content: "sin título"})
+		var msg pb.Error
+		decode(t, serveNote(router, http.MethodPost, "/notes", binding.MIMEPROTOBUF, binding.MIMEPROTOBUF, body), http.StatusBadRequest, &msg)
+		if msg.GetError() == "" {
+			t.Error("el error viene vacío")
+		}
+
+		w := serveNote(router, http.MethodPost, "/notes", binding.MIMEPROTOBUF, binding.MIMEJSON, []byte{0xff, 0xff})
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Protobuf") {
+			t.Errorf("Protobuf corrupto = %d %s", w.Code, w.Body)
+		}
+	})
+}
+
+func TestNoteHandlersMsgPack(t *testing.T) {
+	repo := &fakeNoteRepository{}
+	router := newNoteRouter(repo)
+
+	encode := func(v any) []byte {
+		data, err := marshalMsgPack(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder, status int, v any) {
+		t.Helper()
+		if w.Code != status {
+			t.Fatalf("status = %d, se esperaba %d: %s", w.Code, status, w.Body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != binding.MIMEMSGPACK2 {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		dec := msgpack.NewDecoder(w.Body)
+		dec.SetCustomStructTag("json")
+		if err := dec.Decode(v); err != nil {
+			t.Fatalf("respuesta MessagePack inválida: %v", err)
+		}
+	}
+
+	// Los dos tipos MIME de MessagePack se aceptan en Content-Type y Accept
+	for _, mime := range []string{binding.MIMEMSGPACK2, binding.MIMEMSGPACK} {
+		t.Run(mime, func(t *testing.T) {
+			var note models.Note
+			body := encode(models.CreateNoteRequest{Title: "nueva", Content: "desde msgpack"})
+			decode(t, serveNote(router, http.MethodPost, "/notes", mime, mime, body), http.StatusCreated, &note)
+			if repo.created == nil || repo.created.Content != "desde msgpack" || note.Title != "nueva" || !note.CreatedAt.Equal(noteCreatedAt) {
+				t.Errorf("petición %+v, respuesta %+v", repo.created, note)
+			}
+		})
+	}
+
+	t.Run("GetNote", func(t *testing.T) {
+		var note models.Note
+		decode(t, serveNote(router, http.MethodGet, "/notes/1", "", binding.MIMEMSGPACK2, nil), http.StatusOK, &note)
+		if note.ID != 1 || note.Title != "primera" {
+			t.Errorf("nota = %+v", note)
+		}
+	})
+
+	t.Run("ListNotes", func(t *testing.T) {
+		var page models.NotesPage
+		decode(t, serveNote(router, http.MethodGet, "/notes", "", binding.MIMEMSGPACK2, nil), http.StatusOK, &page)
+		if len(page.Notes) != 2 || !page.NextPage {
+			t.Errorf("página = %+v", page)
+		}
+	})
+
+	t.Run("SearchNotes", func(t *testing.T) {
+		var notes []models.Note
+		decode(t, serveNote(router, http.MethodGet, "/notes/search?q=buscada", "", binding.MIMEMSGPACK2, nil), http.StatusOK, &notes)
+		if len(notes) != 1 || notes[0].Title != "buscada" {
+			t.Errorf("resultado = %+v", notes)
+		}
+	})
+
+	t.Run("GetNotesBatch", func(t *testing.T) {
+		var notes []models.Note
+		decode(t, serveNote(router, http.MethodGet, "/notes/batch?ids=4&ids=5", "", binding.MIMEMSGPACK2, nil), http.StatusOK, &notes)
+		if len(notes) != 2 || len(repo.batch) != 2 || repo.batch[0] != 4 {
+			t.Errorf("batch = %+v (IDs %v)", notes, repo.batch)
+		}
+	})
+
+	t.Run("UpdateNote", func(t *testing.T) {
+		var note models.Note
+		body := encode(models.UpdateNoteRequest{Content: "editada"})
+		decode(t, serveNote(router, http.MethodPut, "/notes/1", binding.MIMEMSGPACK2, binding.MIMEMSGPACK2, body), http.StatusOK, &note)
+		if repo.updated == nil || repo.updated.Content != "editada" {
+			t.Errorf("petición decodificada = %+v", repo.updated)
+		}
+	})
+
+	t.Run("cuerpo inválido", func(t *testing.T) {
+		var msg map[string]string
+		body := encode(models.CreateNoteRequest{Title: strings.Repeat("x", 256), Content: "largo"})
+		decode(t, serveNote(router, http.MethodPost, "/notes", binding.MIMEMSGPACK2, binding.MIMEMSGPACK2, body), http.StatusBadRequest, &msg)
+		if msg["error"] == "" {
+			t.Errorf("error = %v", msg)
+		}
+	})
+}
+
+// Sin mensaje Protobuf para el tipo, la respuesta sale en JSON
+func TestRespondProtobufFallsBackToJSON(t *testing.T) {
+	router := newNoteRouter(&fakeNoteRepository{})
+
+	w := serveNote(router, http.MethodGet, "/stats", "", binding.MIMEPROTOBUF, nil)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), binding.MIMEJSON) {
+		t.Fatalf("stats = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var stats map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || stats["total_notes"] != 3.0 {
+		t.Errorf("stats = %v, %v", stats, err)
+	}
+
+	// Un gin.H que no es un error tampoco tiene mensaje
+	if _, ok := toProto(gin.H{"error": "x", "detalle": "y"}); ok {
+		t.Error("toProto convirtió un gin.H con más campos que error")
+	}
+
+	// Sin Accept, o con un formato no ofrecido, se responde JSON
+	for _, accept := range []string{"", "*/*", "text/html"} {
+		w := serveNote(router, http.MethodGet, "/notes/1", "", accept, nil)
+		body, _ := io.ReadAll(w.Body)
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), binding.MIMEJSON) || !json.Valid(body) {
+			t.Errorf("Accept %q: Content-Type %q", accept, w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed templates/public_note.html
+var templatesFS embed.FS
+
+var publicNoteTemplate = template.Must(template.ParseFS(templatesFS, "templates/public_note.html"))
+
+type ShareHandler struct {
+	repo db.SharingRepository
+}
+
+func NewShareHandler(repo db.SharingRepository) *ShareHandler {
+	return &ShareHandler{repo: repo}
+}
+
+// ShareNote comparte una nota propia con otro usuario
+func (h *ShareHandler) ShareNote(c *gin.Context) {
+	noteID, ok := parseNoteID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ShareNoteRequest
+	if err := bind(c, &req); err != nil {
+		respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.UserID == reqctx.UserID(c.Request.Context()) {
+		respond(c, http.StatusBadRequest, gin.H{"error": "No se puede compartir una nota con su dueño"})
+		return
+	}
+
+	share, err := h.repo.ShareNote(c.Request.Context(), noteID, req)
+	if err != nil {
+		if errors.Is(err, db.ErrNoteNotFound) {
+			respond(c, http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
+			return
+		}
+		serverError(c, "Error compartiendo nota", err)
+		return
+	}
+
+	respond(c, http.StatusOK, share)
+}
+
+// ListShares lista con quién está compartida una nota propia
+func (h *ShareHandler) ListShares(c *gin.Context) {
+	noteID, ok := parseNoteID(c)
+	if !ok {
+		return
+	}
+
+	shares, err := h.repo.ListShares(c.Request.Context(), noteID)
+	if err != nil {
+		if errors.Is(err, db.ErrNoteNotFound) {
+			respond(c, http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
+			return
+		}
+		serverError(c, "Error listando comparticiones", err)
+		return
+	}
+
+	respond(c, http.StatusOK, shares)
+}
+
+// UnshareNote retira el acceso de un usuario a una nota propia
+func (h *ShareHandler) UnshareNote(c *gin.Context) {
+	noteID, ok := parseNoteID(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		respond(c, http.StatusBadRequest, gin.H{"error": "Usuario inválido"})
+		return
+	}
+
+	if err := h.repo.UnshareNote(c.Request.Context(), noteID, userID); err != nil {
+		if errors.Is(err, db.ErrShareNotFound) {
+			respond(c, http.StatusNotFound, gin.H{"error": "Compartición no encontrada"})
+			return
+		}
+		serverError(c, "Error retirando compartición", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListSharedWithMe lista las notas que otros usuarios compartieron conmigo
+func (h *ShareHandler) ListSharedWithMe(c *gin.Context) {
+	notes, err := h.repo.ListSharedWithMe(c.Request.Context())
+	if err != nil {
+		serverError(c, "Error listando notas compartidas", err)
+		return
+	}
+
+	respond(c, http.StatusOK, notes)
+}
+
+// CreatePublicLink genera un enlace público de solo lectura
+func (h *ShareHandler) CreatePublicLink(c *gin.Context) {
+	noteID, ok := parseNoteID(c)
+	if !ok {
+		return
+	}
+
+	// El cuerpo es opcional: sin él el enlace no vence
+	var req models.CreatePublicLinkRequest
+	if c.Request.ContentLength > 0 {
+		if err := bind(c, &req); err != nil {
+			respond(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInHours > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		expiresAt = &t
+	}
+
+	link, err := h.repo.CreatePublicLink(c.Request.Context(), noteID, expiresAt)
+	if err != nil {
+		if errors.Is(err, db.ErrNoteNotFound) {
+			respond(c, http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
+			return
+		}
+		serverError(c, "Error creando enlace", err)
+		return
+	}
+
+	link.URL = "/public/notes/" + link.Token
+	respond(c, http.StatusCreated, link)
+}
+
+// ListPublicLinks lista los enlaces públicos de una nota propia
+func (h *ShareHandler) ListPublicLinks(c *gin.Context) {
+	noteID, ok := parseNoteID(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.repo.ListPublicLinks(c.Request.Context(), noteID)
+	if err != nil {
+		if errors.Is(err, db.ErrNoteNotFound) {
+			respond(c, http.StatusNotFound, gin.H{"error": "Nota no encontrada"})
+			return
+		}
+		serverError(c, "Error listando enlaces", err)
+		return
+	}
+
+	respond(c, http.StatusOK, links)
+}
+
+// RevokePublicLink revoca un enlace público
+func (h *ShareHandler) RevokePublicLink(c *gin.Context) {
+	noteID, ok := parseNoteID(c)
+	if !ok {
+		return
+	}
+
+	linkID, err := strconv.ParseInt(c.Param("link_id"), 10, 64)
+	if err != nil {
+		respond(c, http.StatusBadRequest, gin.H{"error": "Enlace inválido"})
+		return
+	}
+
+	if err := h.repo.RevokePublicLink(c.Request.Context(), noteID, linkID); err != nil {
+		if errors.Is(err, db.ErrLinkNotFound) {
+			respond(c, http.StatusNotFound, gin.H{"error": "Enlace no encontrado"})
+			return
+		}
+		serverError(c, "Error revocando enlace", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetPublicNote muestra una nota en HTML de solo lectura, sin autenticación
+func (h *ShareHandler) GetPublicNote(c *gin.Context) {
+	note, err := h.repo.GetNoteByPublicToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error obteniendo nota pública", "error", err)
+		c.String(http.StatusInternalServerError, "Error obteniendo nota")
+		return
+	}
+
+	if note == nil {
+		c.String(http.StatusNotFound, "Nota no encontrada")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("X-Robots-Tag", "noindex")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := publicNoteTemplate.Execute(c.Writer, note); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error renderizando nota pública", "error", err)
+	}
+}
+
+// parseNoteID lee el parámetro :id y responde 400 si no es válido
+func parseNoteID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respond(c, http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/db"
+	"github.com/ybotet/notes-api-optimization/internal/models"
+	"github.com/ybotet/notes-api-optimization/internal/pb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeSharingRepository registra la última compartición y devuelve datos fijos,
+// o err si está definido (p. ej. el repositorio real con un usuario que no es el dueño)
+type fakeSharingRepository struct {
+	db.SharingRepository
+	err        error
+	shared     *models.ShareNoteRequest
+	publicNote *models.Note
+}
+
+func (r *fakeSharingRepository) ShareNote(_ context.Context, noteID int64, req models.ShareNoteRequest) (*models.NoteShare, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.shared = &req
+	return &models.NoteShare{NoteID: noteID, UserID: req.UserID, Role: req.Role, CreatedAt: time.Now()}, nil
+}
+
+func (r *fakeSharingRepository) UnshareNote(context.Context, int64, int64) error {
+	return r.err
+}
+
+func (r *fakeSharingRepository) CreatePublicLink(_ context.Context, noteID int64, _ *time.Time) (*models.PublicLink, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &models.PublicLink{ID: 1, NoteID: noteID, Token: "token"}, nil
+}
+
+func (r *fakeSharingRepository) RevokePublicLink(context.Context, int64, int64) error {
+	return r.err
+}
+
+func (r *fakeSharingRepository) GetNoteByPublicToken(context.Context, string) (*models.Note, error) {
+	return r.publicNote, r.err
+}
+
+func (r *fakeSharingRepository) ListSharedWithMe(context.Context) ([]models.SharedNote, error) {
+	return []models.SharedNote{{Note: models.Note{ID: 7, Title: "compartida"}, Role: models.RoleEditor}}, nil
+}
+
+func newShareRouter(repo db.SharingRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewShareHandler(repo)
+
+	router := gin.New()
+	router.GET("/notes/shared", h.ListSharedWithMe)
+	router.POST("/notes/:id/shares", h.ShareNote)
+	router.DELETE("/notes/:id/shares/:user_id", h.UnshareNote)
+	router.POST("/notes/:id/links", h.CreatePublicLink)
+	router.DELETE("/notes/:id/links/:link_id", h.RevokePublicLink)
+	router.GET("/public/notes/:token", h.GetPublicNote)
+	return router
+}
+
+func TestShareHandlersNegotiateFormat(t *testing.T) {
+	repo := &fakeSharingRepository{}
+	router := newShareRouter(repo)
+
+	// Cuerpo en Protobuf, respuesta en MessagePack
+	body, err := proto.Marshal(&pb.ShareNoteRequest{UserId: 2, Role: models.RoleViewer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/notes/1/shares", bytes.NewReader(body))
+	req.Header.Set("Content-Type", binding.MIMEPROTOBUF)
+	req.Header.Set("Accept", binding.MIMEMSGPACK2)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /shares = %d: %s", w.Code, w.Body)
+	}
+	if repo.shared == nil || repo.shared.UserID != 2 || repo.shared.Role != models.RoleViewer {
+		t.Errorf("petición decodificada = %+v", repo.shared)
+	}
+	var share models.NoteShare
+	dec := msgpack.NewDecoder(w.Body)
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(&share); err != nil || share.NoteID != 1 || share.UserID != 2 {
+		t.Errorf("respuesta MessagePack = %+v, %v", share, err)
+	}
+
+	// ListSharedWithMe en Protobuf
+	req = httptest.NewRequest(http.MethodGet, "/notes/shared", nil)
+	req.Header.Set("Accept", binding.MIMEPROTOBUF)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var list pb.SharedNoteList
+	if err := proto.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("respuesta Protobuf inválida: %v", err)
+	}
+	if len(list.GetNotes()) != 1 || list.GetNotes()[0].GetNote().GetId() != 7 || list.GetNotes()[0].GetRole() != models.RoleEditor {
+		t.Errorf("SharedNoteList = %v", &list)
+	}
+}
+
+func TestShareHandlersValidateBody(t *testing.T) {
+	router := newShareRouter(&fakeSharingRepository{})
+
+	// El binding se valida también fuera de JSON
+	body, _ := proto.Marshal(&pb.ShareNoteRequest{UserId: 2, Role: "owner"})
+	req := httptest.NewRequest(http.MethodPost, "/notes/1/shares", bytes.NewReader(body))
+	req.Header.Set("Content-Type", binding.MIMEPROTOBUF)
+	req.Header.Set("Accept", binding.MIMEPROTOBUF)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("rol inválido = %d, se esperaba 400", w.Code)
+	}
+	var msg pb.Error
+	if err := proto.Unmarshal(w.Body.Bytes(), &msg); err != nil || msg.GetError() == "" {
+		t.Errorf("error Protobuf = %v, %v", &msg, err)
+	}
+}
+
+// Los errores del repositorio por no ser el dueño o por comparticiones y enlaces
+// inexistentes se responden con 404
+func TestShareHandlersNotFound(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		method string
+		path   string
+		body   string
+	}{
+		{"compartir nota ajena", db.ErrNoteNotFound, http.MethodPost, "/notes/1/shares", `{"user_id":2,"role":"viewer"}`},
+		{"retirar compartición inexistente", db.ErrShareNotFound, http.MethodDelete, "/notes/1/shares/2", ""},
+		{"enlace de nota ajena", db.ErrNoteNotFound, http.MethodPost, "/notes/1/links", ""},
+		{"revocar enlace ajeno o ya revocado", db.ErrLinkNotFound, http.MethodDelete, "/notes/1/links/1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newShareRouter(&fakeSharingRepository{err: tt.err})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", binding.MIMEJSON)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s %s = %d, se esperaba 404", tt.method, tt.path, w.Code)
+			}
+		})
+	}
+}
+
+func TestGetPublicNote(t *testing.T) {
+	// Un token vencido, revocado o desconocido llega como nota nil
+	router := newShareRouter(&fakeSharingRepository{})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/notes/vencido", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("token sin nota = %d, se esperaba 404", w.Code)
+	}
+
+	// La hora se muestra en UTC aunque la nota venga en otra zona
+	zone := time.FixedZone("UTC-3", -3*60*60)
+	note := &models.Note{ID: 1, Title: "pública", UpdatedAt: time.Date(2025, 1, 2, 22, 30, 0, 0, zone)}
+	router = newShareRouter(&fakeSharingRepository{publicNote: note})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/notes/token", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("token vigente = %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "2025-01-03 01:30 UTC") {
+		t.Errorf("falta la hora en UTC:\n%s", w.Body)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Cache-Control = %q, se esperaba no-store", w.Header().Get("Cache-Control"))
+	}
+}
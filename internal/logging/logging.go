@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New crea un logger estructurado.
+// level: debug, info, warn, error. format: json o text.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("nivel de log inválido %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("formato de log inválido %q", format)
+	}
+
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// contextHandler agrega a cada registro los datos de la petición guardados en el contexto
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := reqctx.RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if tenantID := reqctx.TenantID(ctx); tenantID != "" {
+		r.AddAttrs(slog.String("tenant_id", tenantID))
+	}
+	if userID := reqctx.UserID(ctx); userID != 0 {
+		r.AddAttrs(slog.Int64("user_id", userID))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry contiene todas las métricas de la API (sin las globales de client_golang)
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests cuenta las peticiones por ruta, método y código de estado
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Peticiones HTTP atendidas.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration mide la latencia de las peticiones por ruta, método y código de estado
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latencia de las peticiones HTTP.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// GRPCRequests cuenta las llamadas gRPC por método y código de estado
+	GRPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_requests_total",
+		Help: "Llamadas gRPC atendidas.",
+	}, []string{"method", "code"})
+
+	// GRPCDuration mide la latencia de las llamadas gRPC por método y código de estado
+	GRPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_request_duration_seconds",
+		Help:    "Latencia de las llamadas gRPC.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	// RepositoryDuration mide la latencia de cada método del Repository
+	RepositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_query_duration_seconds",
+		Help:    "Latencia de los métodos del repositorio de notas.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method", "outcome"})
+
+	// ReplicaHealthy indica si cada réplica de lectura está en rotación (1) o expulsada (0)
+	ReplicaHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "db_replica_healthy",
+		Help: "Estado de las réplicas de lectura (1 en rotación, 0 expulsada).",
+	}, []string{"replica"})
+
+	// DBReads cuenta las lecturas enrutadas por destino y motivo
+	DBReads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_routed_reads_total",
+		Help: "Lecturas enrutadas a réplicas o a la primaria.",
+	}, []string{"target", "reason"})
+
+	// CacheRequests cuenta los aciertos y fallos de cada cache
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Consultas a los caches por resultado (hit, miss o error).",
+	}, []string{"cache", "result"})
+
+	// CacheEvictions cuenta las entradas expulsadas por capacidad
+	CacheEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_evictions_total",
+		Help: "Entradas expulsadas de los caches por falta de capacidad.",
+	}, []string{"cache"})
+
+	// CoalescedCalls cuenta las lecturas del repositorio que ejecutaron la consulta
+	// (executed) o compartieron la de otra llamada en curso (shared)
+	CoalescedCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_coalesced_calls_total",
+		Help: "Lecturas coalescidas del repositorio por método y resultado (executed o shared).",
+	}, []string{"method", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		GRPCRequests,
+		GRPCDuration,
+		RepositoryDuration,
+		ReplicaHealthy,
+		DBReads,
+		CacheRequests,
+		CacheEvictions,
+		CoalescedCalls,
+	)
+}
+
+// Handler expone el registro en formato de texto de Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
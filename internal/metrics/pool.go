@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector publica las estadísticas de un pgxpool.Pool en cada scrape.
+// Los contadores de pgxpool son acumulativos desde la creación del pool,
+// así que no se pierde nada entre scrapes.
+type PoolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	totalConns           *prometheus.Desc
+	idleConns            *prometheus.Desc
+	acquiredConns        *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	maxConns             *prometheus.Desc
+}
+
+// NewPoolCollector crea un collector para el pool identificado por name (p. ej. "primary")
+func NewPoolCollector(name string, pool *pgxpool.Pool) *PoolCollector {
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("pgxpool_"+metric, help, nil, prometheus.Labels{"pool": name})
+	}
+
+	return &PoolCollector{
+		stat: pool.Stat,
+
+		acquireCount:         desc("acquire_total", "Adquisiciones de conexión exitosas."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Tiempo total esperando adquirir conexiones."),
+		emptyAcquireCount:    desc("empty_acquire_total", "Adquisiciones que tuvieron que esperar por un pool vacío."),
+		canceledAcquireCount: desc("canceled_acquire_total", "Adquisiciones canceladas por el contexto."),
+		totalConns:           desc("conns", "Conexiones abiertas."),
+		idleConns:            desc("idle_conns", "Conexiones ociosas."),
+		acquiredConns:        desc("acquired_conns", "Conexiones en uso."),
+		constructingConns:    desc("constructing_conns", "Conexiones en construcción."),
+		maxConns:             desc("max_conns", "Tamaño máximo del pool."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquireCount
+	ch <- c.canceledAcquireCount
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.acquiredConns
+	ch <- c.constructingConns
+	ch <- c.maxConns
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(s.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+}
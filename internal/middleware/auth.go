@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserIDHeader es el header con el que el gateway identifica al usuario
+const UserIDHeader = "X-User-ID"
+
+// Identity lee el usuario del header X-User-ID y lo guarda en el contexto.
+// Las peticiones sin header continúan como anónimas.
+func Identity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.GetHeader(UserIDHeader)
+		if raw == "" {
+			c.Next()
+			return
+		}
+
+		userID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || userID < 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Usuario inválido"})
+			return
+		}
+
+		ctx := reqctx.WithUserID(c.Request.Context(), userID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequireUser rechaza las peticiones anónimas
+func RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if reqctx.UserID(c.Request.Context()) == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Se requiere usuario"})
+			return
+		}
+		c.Next()
+	}
+}
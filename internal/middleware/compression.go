@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionEncodings son las codificaciones soportadas, en orden de preferencia
+// ante igual q: zstd y br comprimen más que gzip con niveles de CPU parecidos
+var compressionEncodings = []string{"zstd", "br", "gzip"}
+
+// incompressibleTypes son tipos ya comprimidos (exportaciones en zip o tar.gz, imágenes...)
+var incompressibleTypes = []string{
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-tar+gzip",
+	"application/zstd",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"image/",
+	"video/",
+	"audio/",
+}
+
+// CompressionOptions configura la compresión de respuestas
+type CompressionOptions struct {
+	// MinSize es el tamaño mínimo del cuerpo para comprimir: en respuestas chicas
+	// el costo de CPU y los headers no compensan
+	MinSize int
+}
+
+// encoder es la interfaz común de los compresores de gzip, brotli y zstd
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// Compression comprime las respuestas según Accept-Encoding (zstd, br o gzip).
+// El cuerpo se retiene hasta alcanzar MinSize; si la respuesta no llega, sale sin
+// comprimir. No se comprimen las respuestas que ya traen Content-Encoding ni los
+// tipos ya comprimidos. Los compresores se reutilizan con un sync.Pool por codificación.
+func Compression(opts CompressionOptions) gin.HandlerFunc {
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+			return w
+		}},
+		"br": {New: func() any {
+			return brotli.NewWriterLevel(nil, 4)
+		}},
+		"zstd": {New: func() any {
+			w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+			return w
+		}},
+	}
+
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, pool: pools[encoding], minSize: opts.MinSize}
+		c.Writer = cw
+		c.Next()
+		cw.finish()
+		c.Writer = cw.ResponseWriter
+	}
+}
+
+// negotiateEncoding elige la codificación con mayor q de Accept-Encoding ("" si ninguna)
+func negotiateEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range compressionEncodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = max(wildcard, 0)
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter retiene el cuerpo hasta decidir si comprime y luego escribe
+// a través del compresor
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	pool     *sync.Pool
+	minSize  int
+
+	buf     []byte
+	decided bool
+	encoder encoder
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minSize {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow envía los headers ya (respuestas sin cuerpo): no se comprime
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided && w.ResponseWriter.Written() || len(w.buf) > 0
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.minSize)
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish decide con el cuerpo completo si aún no se alcanzó MinSize y cierra el compresor
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.encoder.Reset(nil)
+		w.pool.Put(w.encoder)
+		w.encoder = nil
+	}
+}
+
+// decide fija los headers, envía el cuerpo retenido y, si comprime, prepara el compresor
+func (w *compressWriter) decide(bigEnough bool) error {
+	w.decided = true
+	header := w.Header()
+
+	if compressible(w.Status(), header) {
+		header.Add("Vary", "Accept-Encoding")
+		if bigEnough {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			// El cuerpo comprimido no es idéntico byte a byte: el ETag pasa a débil
+			if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				header.Set("ETag", "W/"+etag)
+			}
+			w.encoder = w.pool.Get().(encoder)
+			w.encoder.Reset(w.ResponseWriter)
+		}
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func compressible(status int, header http.Header) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	for _, t := range incompressibleTypes {
+		if mediaType == t || strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) {
+			return false
+		}
+	}
+	return true
+}
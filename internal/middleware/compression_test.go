@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/gzip"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"GZIP", "gzip"},
+		{"deflate", ""},
+		// Ante igual q gana el orden de preferencia: zstd, br, gzip
+		{"gzip, br", "br"},
+		{"gzip, br, zstd", "zstd"},
+		// q-values
+		{"gzip;q=1.0, br;q=0.5", "gzip"},
+		{"gzip ; q=0.8, zstd;q=0.9", "zstd"},
+		{"gzip;q=0.001", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"gzip;q=0", ""},
+		{"gzip;q=abc", "gzip"},
+		// identity no es una compresión: con q=0 se elige igual la mejor disponible
+		{"identity", ""},
+		{"identity;q=0", ""},
+		{"identity;q=0, gzip;q=0.5", "gzip"},
+		// Comodín
+		{"*", "zstd"},
+		{"*;q=0", ""},
+		{"*, zstd;q=0", "br"},
+		{"*;q=0.5, gzip;q=0.8", "gzip"},
+		{"*;q=0, gzip", "gzip"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, se esperaba %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressionSkipsCompressedBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := strings.Repeat("nota ", 200)
+
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		compress    bool
+	}{
+		{"json", "application/json; charset=utf-8", "", true},
+		{"exportación zip", "application/zip", "", false},
+		{"tar.gz", "application/gzip", "", false},
+		{"imagen", "image/png", "", false},
+		{"ya trae Content-Encoding", "application/json", "br", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Compression(CompressionOptions{MinSize: 256}))
+			router.GET("/", func(c *gin.Context) {
+				if tt.encoding != "" {
+					c.Header("Content-Encoding", tt.encoding)
+				}
+				c.Data(http.StatusOK, tt.contentType, []byte(body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get("Content-Encoding")
+			if !tt.compress {
+				if got != tt.encoding {
+					t.Errorf("Content-Encoding = %q, se esperaba %q", got, tt.encoding)
+				}
+				if w.Body.String() != body {
+					t.Error("el cuerpo se modificó")
+				}
+				return
+			}
+
+			if got != "gzip" {
+				t.Fatalf("Content-Encoding = %q, se esperaba gzip", got)
+			}
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			plain, err := io.ReadAll(zr)
+			if err != nil || string(plain) != body {
+				t.Errorf("cuerpo descomprimido distinto: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompressionMinSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compression(CompressionOptions{MinSize: 256}))
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "chica")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q en una respuesta menor que MinSize", got)
+	}
+	if w.Body.String() != "chica" {
+		t.Errorf("cuerpo = %q", w.Body)
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Vary = %q, se esperaba Accept-Encoding", w.Header().Get("Vary"))
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader es el header con el que el cliente identifica un reintento
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyStore persiste las claves de idempotencia y sus respuestas
+type IdempotencyStore interface {
+	Claim(ctx context.Context, key, requestHash string, ttl, lease time.Duration) (*models.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, key string, status int, contentType string, body []byte) error
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency hace que las peticiones POST con Idempotency-Key se ejecuten una sola vez:
+// los reintentos con el mismo cuerpo reciben la respuesta guardada, la reutilización
+// de la clave con otro cuerpo o con otro Accept (la respuesta guardada está en el
+// formato negociado) se rechaza con 422 y un duplicado en curso recibe 409.
+// Una reserva en curso dura lease: si el proceso muere sin liberarla, al vencer
+// otra petición con la misma clave puede tomarla.
+func Idempotency(store IdempotencyStore, ttl, lease time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key demasiado larga"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Error leyendo cuerpo"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		requestHash := hashRequest(c.Request.Method, c.Request.URL.Path, c.GetHeader("Accept"), body)
+
+		record, claimed, err := store.Claim(ctx, key, requestHash, ttl, lease)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Error verificando Idempotency-Key"})
+			return
+		}
+
+		if !claimed {
+			switch {
+			case record.RequestHash != requestHash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key reutilizada con otra petición"})
+			case record.Status != models.IdempotencyCompleted:
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Petición con la misma Idempotency-Key en curso"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.ResponseStatus, record.ContentType, record.ResponseBody)
+				c.Abort()
+			}
+			return
+		}
+
+		release := func() {
+			if err := store.Release(context.WithoutCancel(ctx), key); err != nil {
+				slog.ErrorContext(ctx, "error liberando Idempotency-Key", "error", err)
+			}
+		}
+
+		// Un panic del handler no pasa por el código que sigue a c.Next():
+		// liberar la clave antes de que lo atrape Recovery
+		defer func() {
+			if r := recover(); r != nil {
+				release()
+				panic(r)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Un error del servidor no se guarda: el cliente puede reintentar con la misma clave
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		contentType := recorder.Header().Get("Content-Type")
+		if err := store.Complete(context.WithoutCancel(ctx), key, status, contentType, recorder.body.Bytes()); err != nil {
+			slog.ErrorContext(ctx, "error guardando respuesta idempotente", "error", err)
+		}
+	}
+}
+
+// hashRequest identifica la petición; incluye Accept porque determina el formato de la respuesta
+func hashRequest(method, path, accept string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n" + accept + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyRecorder copia el cuerpo de la respuesta mientras se escribe al cliente
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore implementa IdempotencyStore en memoria
+type memoryIdempotencyStore struct {
+	mu       sync.Mutex
+	records  map[string]*models.IdempotencyRecord
+	released []string
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+}
+
+func (s *memoryIdempotencyStore) Claim(_ context.Context, key, requestHash string, _, _ time.Duration) (*models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok {
+		copied := *record
+		return &copied, false, nil
+	}
+	record := &models.IdempotencyRecord{Key: key, RequestHash: requestHash, Status: models.IdempotencyInProgress}
+	s.records[key] = record
+	copied := *record
+	return &copied, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, key string, status int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.records[key]
+	record.Status = models.IdempotencyCompleted
+	record.ResponseStatus = status
+	record.ContentType = contentType
+	record.ResponseBody = body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	s.released = append(s.released, key)
+	return nil
+}
+
+func idempotentPost(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryIdempotencyStore()
+	calls := 0
+
+	router := gin.New()
+	router.Use(Idempotency(store, time.Hour, time.Minute))
+	router.POST("/notes", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	first := idempotentPost(router, "k1", `{"title":"a"}`)
+	second := idempotentPost(router, "k1", `{"title":"a"}`)
+	if calls != 1 {
+		t.Fatalf("el handler se ejecutó %d veces", calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, se esperaba %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("falta Idempotent-Replayed en el replay")
+	}
+
+	if w := idempotentPost(router, "k1", `{"title":"b"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("misma clave con otro cuerpo = %d, se esperaba 422", w.Code)
+	}
+}
+
+// Un panic del handler debe liberar la clave: si no, los reintentos reciben 409
+// hasta que vence la reserva
+func TestIdempotencyReleasesOnPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryIdempotencyStore()
+	fail := true
+
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.Use(Idempotency(store, time.Hour, time.Minute))
+	router.POST("/notes", func(c *gin.Context) {
+		if fail {
+			panic("fallo")
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	if w := idempotentPost(router, "k1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("primer intento = %d, se esperaba 500", w.Code)
+	}
+	if len(store.released) != 1 || store.released[0] != "k1" {
+		t.Fatalf("claves liberadas = %v, se esperaba [k1]", store.released)
+	}
+
+	fail = false
+	if w := idempotentPost(router, "k1", `{}`); w.Code != http.StatusCreated {
+		t.Errorf("reintento = %d, se esperaba 201", w.Code)
+	}
+}
+
+// La respuesta guardada está en el formato negociado: otro Accept es otra petición
+func TestIdempotencyRejectsOtherAccept(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryIdempotencyStore()
+
+	router := gin.New()
+	router.Use(Idempotency(store, time.Hour, time.Minute))
+	router.POST("/notes", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	post := func(accept string) int {
+		req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post("application/json"); code != http.StatusCreated {
+		t.Fatalf("primer intento = %d, se esperaba 201", code)
+	}
+	if code := post("application/x-msgpack"); code != http.StatusUnprocessableEntity {
+		t.Errorf("misma clave con otro Accept = %d, se esperaba 422", code)
+	}
+	if code := post("application/json"); code != http.StatusCreated {
+		t.Errorf("reintento con el mismo Accept = %d, se esperaba el replay 201", code)
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics registra el número y la latencia de las peticiones por ruta y código de estado.
+// Se usa la ruta de gin (/notes/:id) y no la URL para acotar la cardinalidad.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
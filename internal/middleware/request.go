@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader es el header con el ID de correlación de la petición
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID acepta el X-Request-ID del cliente (si es válido) o genera uno nuevo,
+// lo guarda en el contexto y lo devuelve en la respuesta
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := RequestIDOrNew(c.GetHeader(RequestIDHeader))
+
+		c.Header(RequestIDHeader, requestID)
+		ctx := reqctx.WithRequestID(c.Request.Context(), requestID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// ClientIP guarda la IP del cliente (según la configuración de proxies de gin) en el contexto
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := reqctx.WithClientIP(c.Request.Context(), c.ClientIP())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequestIDOrNew retorna el ID recibido si es válido o uno nuevo (también lo usa la API gRPC)
+func RequestIDOrNew(requestID string) string {
+	if requestIDPattern.MatchString(requestID) {
+		return requestID
+	}
+	return newRequestID()
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
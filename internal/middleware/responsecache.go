@@ -0,0 +1,313 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ybotet/notes-api-optimization/internal/cache"
+	"github.com/ybotet/notes-api-optimization/internal/metrics"
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseCacheName es la etiqueta de las métricas del cache de respuestas
+const responseCacheName = "responses"
+
+// revalidateTimeout acota la revalidación en segundo plano de una respuesta vencida
+const revalidateTimeout = 10 * time.Second
+
+// responseVary son los headers de los que depende la respuesta; forman parte de la clave
+var responseVary = []string{TenantIDHeader, UserIDHeader, "Accept"}
+
+// ResponseCacheOptions configura el cache de respuestas
+type ResponseCacheOptions struct {
+	Size int
+	// MaxAge es el tiempo durante el que la respuesta es fresca
+	MaxAge time.Duration
+	// StaleWhileRevalidate es el tiempo extra durante el que se sirve vencida
+	// mientras se revalida en segundo plano
+	StaleWhileRevalidate time.Duration
+}
+
+// ResponseCache cachea en memoria las respuestas 200 de rutas GET, por ruta, query
+// normalizada, tenant y usuario. Respeta Cache-Control del cliente (no-store, no-cache,
+// max-age), sirve respuestas vencidas mientras las revalida y emite Cache-Control,
+// ETag y Vary para que los proxies intermedios también cacheen. Las respuestas de
+// un usuario identificado (X-User-ID) incluyen notas privadas: se marcan private
+// para que solo las guarde el cliente.
+//
+// Las escrituras exitosas del tenant (Invalidate) descartan sus respuestas; las de
+// otras instancias llegan con InvalidateTenant o quedan acotadas por MaxAge.
+// Un ResponseCache nil no cachea.
+type ResponseCache struct {
+	handler http.Handler
+	opts    ResponseCacheOptions
+	entries *cache.LRU[string, *cachedResponse]
+
+	// generations guarda la generación de cada tenant (tenant -> *atomic.Uint64):
+	// forma parte de la clave, así invalidar no necesita recorrer el cache
+	generations sync.Map
+	// refreshing evita revalidar dos veces la misma clave a la vez
+	refreshing sync.Map
+}
+
+type cachedResponse struct {
+	status      int
+	contentType string
+	body        []byte
+	etag        string
+	storedAt    time.Time
+}
+
+// NewResponseCache crea el cache; handler (el router) atiende las revalidaciones
+func NewResponseCache(handler http.Handler, opts ResponseCacheOptions) *ResponseCache {
+	return &ResponseCache{
+		handler: handler,
+		opts:    opts,
+		entries: cache.NewLRU[string, *cachedResponse](opts.Size, opts.MaxAge+opts.StaleWhileRevalidate, func(string) {
+			metrics.CacheEvictions.WithLabelValues(responseCacheName).Inc()
+		}),
+	}
+}
+
+// Handler cachea la ruta GET en la que se monta
+func (rc *ResponseCache) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rc == nil || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		directives := parseCacheControl(c.GetHeader("Cache-Control"))
+		if directives.noStore {
+			c.Next()
+			return
+		}
+
+		key := rc.key(c)
+		if !directives.noCache {
+			if entry, ok := rc.entries.Get(key); ok {
+				age := time.Since(entry.storedAt)
+				acceptable := directives.maxAge < 0 || age <= directives.maxAge
+
+				switch {
+				case age < rc.opts.MaxAge && acceptable:
+					metrics.CacheRequests.WithLabelValues(responseCacheName, "hit").Inc()
+					rc.serve(c, entry, "HIT")
+					return
+				case directives.maxAge < 0:
+					// Vencida dentro de stale-while-revalidate (si no, el LRU ya la expulsó)
+					metrics.CacheRequests.WithLabelValues(responseCacheName, "stale").Inc()
+					rc.serve(c, entry, "STALE")
+					rc.revalidate(c.Request, key)
+					return
+				}
+			}
+		}
+		metrics.CacheRequests.WithLabelValues(responseCacheName, "miss").Inc()
+
+		buffer := &responseBuffer{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = buffer
+		// Si el handler hace panic se descarta lo retenido: Recovery responde al cliente
+		defer func() { c.Writer = buffer.ResponseWriter }()
+		c.Next()
+		c.Writer = buffer.ResponseWriter
+
+		// Solo se cachean respuestas 200 en las que el handler no definió su propia política
+		if buffer.status != http.StatusOK || c.Writer.Header().Get("Cache-Control") != "" {
+			c.Writer.WriteHeader(buffer.status)
+			c.Writer.Write(buffer.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(buffer.body.Bytes())
+		entry := &cachedResponse{
+			status:      buffer.status,
+			contentType: c.Writer.Header().Get("Content-Type"),
+			body:        buffer.body.Bytes(),
+			etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+			storedAt:    time.Now(),
+		}
+		rc.entries.Set(key, entry)
+		rc.serve(c, entry, "MISS")
+	}
+}
+
+// Invalidate descarta las respuestas cacheadas del tenant tras una escritura exitosa
+func (rc *ResponseCache) Invalidate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if rc == nil || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		rc.InvalidateTenant(reqctx.TenantID(c.Request.Context()))
+	}
+}
+
+// InvalidateTenant descarta las respuestas cacheadas del tenant
+func (rc *ResponseCache) InvalidateTenant(tenantID string) {
+	if rc == nil {
+		return
+	}
+	rc.generation(tenantID).Add(1)
+}
+
+// Purge descarta todas las respuestas cacheadas
+func (rc *ResponseCache) Purge() {
+	if rc == nil {
+		return
+	}
+	rc.entries.Purge()
+}
+
+func (rc *ResponseCache) generation(tenantID string) *atomic.Uint64 {
+	g, _ := rc.generations.LoadOrStore(tenantID, new(atomic.Uint64))
+	return g.(*atomic.Uint64)
+}
+
+// key normaliza la petición: ruta de gin con sus parámetros, query ordenada sin
+// valores vacíos, tenant con su generación, usuario y formato pedido (Accept)
+func (rc *ResponseCache) key(c *gin.Context) string {
+	ctx := c.Request.Context()
+	tenantID := reqctx.TenantID(ctx)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%d|%d|%s|%s", tenantID, rc.generation(tenantID).Load(), reqctx.UserID(ctx),
+		c.GetHeader("Accept"), c.FullPath())
+	for _, p := range c.Params {
+		fmt.Fprintf(&b, "|%s=%s", p.Key, p.Value)
+	}
+
+	query := c.Request.URL.Query()
+	normalized := make(url.Values, len(query))
+	for name, values := range query {
+		for _, v := range values {
+			if v != "" {
+				normalized[name] = append(normalized[name], v)
+			}
+		}
+		sort.Strings(normalized[name])
+	}
+	b.WriteString("?" + normalized.Encode())
+	return b.String()
+}
+
+// serve escribe la respuesta cacheada, o 304 si el cliente ya tiene esa versión
+func (rc *ResponseCache) serve(c *gin.Context, entry *cachedResponse, result string) {
+	scope := "public"
+	if reqctx.UserID(c.Request.Context()) != 0 {
+		scope = "private"
+	}
+
+	header := c.Writer.Header()
+	header.Set("Cache-Control", fmt.Sprintf("%s, max-age=%d, stale-while-revalidate=%d",
+		scope, int(rc.opts.MaxAge.Seconds()), int(rc.opts.StaleWhileRevalidate.Seconds())))
+	header.Set("Vary", strings.Join(responseVary, ", "))
+	header.Set("ETag", entry.etag)
+	header.Set("Age", strconv.Itoa(int(time.Since(entry.storedAt).Seconds())))
+	header.Set("X-Cache", result)
+
+	if etagMatches(c.GetHeader("If-None-Match"), entry.etag) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		c.Abort()
+		return
+	}
+
+	c.Data(entry.status, entry.contentType, entry.body)
+	c.Abort()
+}
+
+// revalidate repite la petición en segundo plano con no-cache, que guarda la respuesta nueva
+func (rc *ResponseCache) revalidate(r *http.Request, key string) {
+	if _, busy := rc.refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), revalidateTimeout)
+	req := r.Clone(ctx)
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Del("If-None-Match")
+
+	go func() {
+		defer cancel()
+		defer rc.refreshing.Delete(key)
+		rc.handler.ServeHTTP(discardResponse{header: make(http.Header)}, req)
+	}()
+}
+
+type cacheControl struct {
+	noStore bool
+	noCache bool
+	maxAge  time.Duration // -1: no indicado
+}
+
+func parseCacheControl(value string) cacheControl {
+	cc := cacheControl{maxAge: -1}
+	for _, directive := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-store":
+			cc.noStore = true
+		case "no-cache":
+			cc.noCache = true
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil && seconds >= 0 {
+				cc.maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return cc
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// responseBuffer retiene la respuesta del handler para poder cachearla y
+// agregarle headers que dependen del cuerpo (ETag)
+type responseBuffer struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseBuffer) WriteHeader(code int)              { w.status = code }
+func (w *responseBuffer) WriteHeaderNow()                   {}
+func (w *responseBuffer) Write(data []byte) (int, error)    { return w.body.Write(data) }
+func (w *responseBuffer) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+func (w *responseBuffer) Status() int                       { return w.status }
+func (w *responseBuffer) Size() int                         { return w.body.Len() }
+func (w *responseBuffer) Written() bool                     { return w.body.Len() > 0 }
+
+// discardResponse recibe la respuesta de una revalidación, que solo importa por el cache
+type discardResponse struct {
+	header http.Header
+}
+
+func (w discardResponse) Header() http.Header         { return w.header }
+func (w discardResponse) Write(b []byte) (int, error) { return len(b), nil }
+func (w discardResponse) WriteHeader(int)             {}
@@ -0,0 +1,288 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cachedRouter monta el cache de respuestas como en las rutas de la API, con
+// handlers que cuentan sus llamadas
+type cachedRouter struct {
+	router *gin.Engine
+	rc     *ResponseCache
+	calls  atomic.Int32
+}
+
+func newCachedRouter(opts ResponseCacheOptions) *cachedRouter {
+	gin.SetMode(gin.TestMode)
+	cr := &cachedRouter{router: gin.New()}
+	cr.rc = NewResponseCache(cr.router, opts)
+
+	cr.router.Use(Recovery(slog.New(slog.DiscardHandler)), Tenant("default"), Identity(), cr.rc.Invalidate())
+	cr.router.GET("/notes", cr.rc.Handler(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"call": cr.calls.Add(1)})
+	})
+	cr.router.GET("/error", cr.rc.Handler(), func(c *gin.Context) {
+		cr.calls.Add(1)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "falla"})
+	})
+	cr.router.GET("/private", cr.rc.Handler(), func(c *gin.Context) {
+		cr.calls.Add(1)
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, gin.H{"call": cr.calls.Load()})
+	})
+	cr.router.GET("/panic", cr.rc.Handler(), func(c *gin.Context) {
+		c.String(http.StatusOK, "parcial")
+		panic("handler roto")
+	})
+	cr.router.POST("/notes", func(c *gin.Context) {
+		if c.Query("fail") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "inválida"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+	return cr
+}
+
+// do ejecuta la petición; headers alterna nombre y valor
+func (cr *cachedRouter) do(method, target string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	cr.router.ServeHTTP(w, req)
+	return w
+}
+
+func expectCache(t *testing.T, w *httptest.ResponseRecorder, status int, result string) {
+	t.Helper()
+	if w.Code != status {
+		t.Errorf("status = %d, se esperaba %d", w.Code, status)
+	}
+	if got := w.Header().Get("X-Cache"); got != result {
+		t.Errorf("X-Cache = %q, se esperaba %q", got, result)
+	}
+}
+
+func TestResponseCacheControlScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	rc := NewResponseCache(router, ResponseCacheOptions{Size: 10, MaxAge: time.Minute, StaleWhileRevalidate: time.Minute})
+	router.Use(Identity())
+	router.GET("/notes", rc.Handler(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"notes": []string{}})
+	})
+
+	tests := []struct {
+		name   string
+		userID string
+		scope  string
+	}{
+		{"anónimo", "", "public"},
+		{"usuario identificado", "42", "private"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// La segunda petición sale del cache
+			for _, result := range []string{"MISS", "HIT"} {
+				req := httptest.NewRequest(http.MethodGet, "/notes", nil)
+				if tt.userID != "" {
+					req.Header.Set(UserIDHeader, tt.userID)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if got := w.Header().Get("X-Cache"); got != result {
+					t.Errorf("X-Cache = %q, se esperaba %q", got, result)
+				}
+				if cc := w.Header().Get("Cache-Control"); !strings.HasPrefix(cc, tt.scope+",") {
+					t.Errorf("Cache-Control = %q, se esperaba %s", cc, tt.scope)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseCacheHitMiss(t *testing.T) {
+	cr := newCachedRouter(ResponseCacheOptions{Size: 10, MaxAge: time.Minute})
+
+	first := cr.do(http.MethodGet, "/notes?limit=5&cursor_id=")
+	expectCache(t, first, http.StatusOK, "MISS")
+
+	// La query se normaliza: orden y valores vacíos no cambian la clave
+	second := cr.do(http.MethodGet, "/notes?cursor_id=&limit=5")
+	expectCache(t, second, http.StatusOK, "HIT")
+	if second.Body.String() != first.Body.String() || cr.calls.Load() != 1 {
+		t.Errorf("el HIT llamó al handler (%d llamadas) o cambió el cuerpo", cr.calls.Load())
+	}
+	if second.Header().Get("ETag") == "" || second.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Errorf("ETag = %q / %q", first.Header().Get("ETag"), second.Header().Get("ETag"))
+	}
+
+	// Otra query, otro Accept u otro tenant son otra entrada
+	expectCache(t, cr.do(http.MethodGet, "/notes?limit=6"), http.StatusOK, "MISS")
+	expectCache(t, cr.do(http.MethodGet, "/notes?limit=5", "Accept", "application/x-msgpack"), http.StatusOK, "MISS")
+	expectCache(t, cr.do(http.MethodGet, "/notes?limit=5", TenantIDHeader, "acme"), http.StatusOK, "MISS")
+}
+
+func TestResponseCacheStaleWhileRevalidate(t *testing.T) {
+	const maxAge = 50 * time.Millisecond
+	cr := newCachedRouter(ResponseCacheOptions{Size: 10, MaxAge: maxAge, StaleWhileRevalidate: time.Minute})
+
+	first := cr.do(http.MethodGet, "/notes")
+	expectCache(t, first, http.StatusOK, "MISS")
+	time.Sleep(maxAge + 10*time.Millisecond)
+
+	// Vencida: se sirve la copia anterior y se revalida en segundo plano
+	stale := cr.do(http.MethodGet, "/notes")
+	expectCache(t, stale, http.StatusOK, "STALE")
+	if stale.Body.String() != first.Body.String() {
+		t.Errorf("STALE = %s, se esperaba la copia anterior %s", stale.Body, first.Body)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for cr.calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if cr.calls.Load() != 2 {
+		t.Fatalf("la revalidación no llamó al handler (%d llamadas)", cr.calls.Load())
+	}
+
+	// La revalidación guardó la respuesta nueva
+	for time.Now().Before(deadline) {
+		fresh := cr.do(http.MethodGet, "/notes")
+		if fresh.Header().Get("X-Cache") == "HIT" {
+			if fresh.Body.String() == first.Body.String() {
+				t.Error("el HIT después de revalidar sirve la copia anterior")
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("la respuesta revalidada no llegó al cache")
+}
+
+func TestResponseCacheClientDirectives(t *testing.T) {
+	cr := newCachedRouter(ResponseCacheOptions{Size: 10, MaxAge: time.Minute, StaleWhileRevalidate: time.Minute})
+
+	// no-store: ni lee ni guarda
+	w := cr.do(http.MethodGet, "/notes", "Cache-Control", "no-store")
+	expectCache(t, w, http.StatusOK, "")
+	expectCache(t, cr.do(http.MethodGet, "/notes"), http.StatusOK, "MISS")
+
+	tests := []struct {
+		cacheControl string
+		result       string
+		calls        int32
+	}{
+		{"max-age=60", "HIT", 2},
+		// Más vieja que lo que acepta el cliente: no se sirve ni vencida
+		{"max-age=0", "MISS", 3},
+		{"no-cache", "MISS", 4},
+		{"", "HIT", 4},
+	}
+	for _, tt := range tests {
+		time.Sleep(5 * time.Millisecond)
+		w := cr.do(http.MethodGet, "/notes", "Cache-Control", tt.cacheControl)
+		expectCache(t, w, http.StatusOK, tt.result)
+		if cr.calls.Load() != tt.calls {
+			t.Errorf("Cache-Control %q: %d llamadas al handler, se esperaban %d", tt.cacheControl, cr.calls.Load(), tt.calls)
+		}
+	}
+}
+
+func TestResponseCacheConditional(t *testing.T) {
+	cr := newCachedRouter(ResponseCacheOptions{Size: 10, MaxAge: time.Minute})
+	etag := cr.do(http.MethodGet, "/notes").Header().Get("ETag")
+
+	tests := []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{`"otra", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"otra"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := cr.do(http.MethodGet, "/notes", "If-None-Match", tt.ifNoneMatch)
+		if w.Code != tt.status {
+			t.Errorf("If-None-Match %s = %d, se esperaba %d", tt.ifNoneMatch, w.Code, tt.status)
+		}
+		if tt.status == http.StatusNotModified && (w.Body.Len() != 0 || w.Header().Get("ETag") != etag) {
+			t.Errorf("304 con cuerpo %q o ETag %q", w.Body, w.Header().Get("ETag"))
+		}
+	}
+}
+
+// Las respuestas que no son 200 o traen su propio Cache-Control pasan sin cachear
+func TestResponseCacheBypass(t *testing.T) {
+	cr := newCachedRouter(ResponseCacheOptions{Size: 10, MaxAge: time.Minute})
+
+	for i := range 2 {
+		w := cr.do(http.MethodGet, "/error")
+		expectCache(t, w, http.StatusInternalServerError, "")
+		if !strings.Contains(w.Body.String(), "falla") {
+			t.Errorf("cuerpo = %s", w.Body)
+		}
+
+		w = cr.do(http.MethodGet, "/private")
+		expectCache(t, w, http.StatusOK, "")
+		if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("Cache-Control = %q, se esperaba el del handler", cc)
+		}
+		if want := fmt.Sprintf(`{"call":%d}`, 2*(i+1)); w.Body.String() != want {
+			t.Errorf("cuerpo = %s, se esperaba %s", w.Body, want)
+		}
+	}
+	if cr.calls.Load() != 4 {
+		t.Errorf("%d llamadas al handler, se esperaban 4", cr.calls.Load())
+	}
+}
+
+func TestResponseCachePanic(t *testing.T) {
+	cr := newCachedRouter(ResponseCacheOptions{Size: 10, MaxAge: time.Minute})
+
+	w := cr.do(http.MethodGet, "/panic")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Error interno") {
+		t.Errorf("panic = %d %q, se esperaba el 500 de Recovery", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "parcial") {
+		t.Errorf("se envió la respuesta parcial del handler: %q", w.Body)
+	}
+}
+
+func TestResponseCacheInvalidation(t *testing.T) {
+	cr := newCachedRouter(ResponseCacheOptions{Size: 10, MaxAge: time.Minute})
+	acme := []string{TenantIDHeader, "acme"}
+
+	cr.do(http.MethodGet, "/notes")
+	cr.do(http.MethodGet, "/notes", acme...)
+
+	// Una escritura fallida no invalida
+	cr.do(http.MethodPost, "/notes?fail=1")
+	expectCache(t, cr.do(http.MethodGet, "/notes"), http.StatusOK, "HIT")
+
+	// Una escritura exitosa invalida solo su tenant
+	cr.do(http.MethodPost, "/notes")
+	expectCache(t, cr.do(http.MethodGet, "/notes"), http.StatusOK, "MISS")
+	expectCache(t, cr.do(http.MethodGet, "/notes", acme...), http.StatusOK, "HIT")
+
+	// Cambios avisados por otra instancia
+	cr.rc.InvalidateTenant("acme")
+	expectCache(t, cr.do(http.MethodGet, "/notes", acme...), http.StatusOK, "MISS")
+	expectCache(t, cr.do(http.MethodGet, "/notes"), http.StatusOK, "HIT")
+}
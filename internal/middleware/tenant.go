@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/ybotet/notes-api-optimization/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantIDHeader es el header que identifica el workspace del cliente
+const TenantIDHeader = "X-Tenant-ID"
+
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Tenant guarda el tenant de la petición en el contexto.
+// Si el header no viene se usa defaultTenant; si defaultTenant está vacío el header es obligatorio.
+func Tenant(defaultTenant string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := c.GetHeader(TenantIDHeader)
+		if tenantID == "" {
+			tenantID = defaultTenant
+		}
+
+		if !ValidTenantID(tenantID) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Tenant inválido"})
+			return
+		}
+
+		ctx := reqctx.WithTenantID(c.Request.Context(), tenantID)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// ValidTenantID indica si el tenant tiene un formato aceptable (también lo usa la API gRPC)
+func ValidTenantID(tenantID string) bool {
+	return tenantIDPattern.MatchString(tenantID)
+}
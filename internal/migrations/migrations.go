@@ -0,0 +1,277 @@
+// Package migrations aplica el esquema de la base con migraciones numeradas.
+//
+// Cada migración es un par sql/NNNN_nombre.up.sql y sql/NNNN_nombre.down.sql embebido
+// en el binario. Las versiones aplicadas se registran en schema_migrations y un
+// advisory lock impide que dos procesos (p. ej. varias réplicas con AUTO_MIGRATE)
+// migren a la vez. Cada migración corre en su propia transacción, salvo que su
+// primera línea sea "-- migrate:no-transaction" (necesario para CREATE INDEX CONCURRENTLY).
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey identifica el advisory lock de las migraciones (arbitrario pero fijo)
+const lockKey int64 = 7_305_042_919
+
+const noTransaction = "-- migrate:no-transaction"
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status es el estado de una migración en la base
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+// New carga las migraciones embebidas. El pool debe usar un rol con permisos
+// sobre el esquema (dueño de las tablas), no el rol de la aplicación.
+func New(pool *pgxpool.Pool, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: migrations, logger: logger}, nil
+}
+
+// load lee los pares up/down y los ordena por versión
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo migraciones: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		m := fileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("nombre de migración inválido: %s", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		data, err := fs.ReadFile(fsys, "sql/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("versión %d duplicada: %s y %s", version, migration.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("la migración %04d_%s no tiene up y down", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up aplica todas las migraciones pendientes y devuelve cuántas aplicó
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *pgx.Conn, appliedAt map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := appliedAt[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down revierte las últimas steps migraciones aplicadas y devuelve cuántas revirtió
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.withLock(ctx, func(conn *pgx.Conn, appliedAt map[int64]time.Time) error {
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := appliedAt[migration.Version]; !ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lista todas las migraciones conocidas con su fecha de aplicación (nil si está pendiente)
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(_ *pgx.Conn, appliedAt map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if at, ok := appliedAt[migration.Version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock toma el advisory lock en una conexión dedicada, asegura schema_migrations
+// y ejecuta fn con las versiones ya aplicadas y su fecha
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgx.Conn, appliedAt map[int64]time.Time) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("error adquiriendo conexión: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("error tomando el lock de migraciones: %w", err)
+	}
+	defer func() {
+		// Si el contexto ya fue cancelado se libera igual; al cerrar la conexión también se libera
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			m.logger.Warn("error liberando el lock de migraciones", "error", err)
+		}
+	}()
+
+	_, err = conn.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version BIGINT PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("error creando schema_migrations: %w", err)
+	}
+
+	appliedAt, err := appliedVersions(ctx, conn.Conn())
+	if err != nil {
+		return err
+	}
+
+	return fn(conn.Conn(), appliedAt)
+}
+
+func appliedVersions(ctx context.Context, conn *pgx.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("error escaneando schema_migrations: %w", err)
+		}
+		applied[version] = at
+	}
+
+	return applied, rows.Err()
+}
+
+// apply ejecuta el up o el down de una migración y actualiza schema_migrations
+func (m *Migrator) apply(ctx context.Context, conn *pgx.Conn, migration Migration, up bool) error {
+	sql, direction := migration.Up, "up"
+	record := func(ctx context.Context, exec execer) error {
+		_, err := exec.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+			migration.Version, migration.Name)
+		return err
+	}
+	if !up {
+		sql, direction = migration.Down, "down"
+		record = func(ctx context.Context, exec execer) error {
+			_, err := exec.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+			return err
+		}
+	}
+
+	start := time.Now()
+	label := fmt.Sprintf("%04d_%s", migration.Version, migration.Name)
+
+	if strings.HasPrefix(strings.TrimSpace(sql), noTransaction) {
+		// Sin transacción: si falla a mitad, la migración queda sin registrar y hay que revisarla a mano
+		if _, err := conn.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("error en migración %s (%s): %w", label, direction, err)
+		}
+		if err := record(ctx, conn); err != nil {
+			return fmt.Errorf("error registrando migración %s: %w", label, err)
+		}
+	} else {
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, sql); err != nil {
+				return fmt.Errorf("error en migración %s (%s): %w", label, direction, err)
+			}
+			if err := record(ctx, tx); err != nil {
+				return fmt.Errorf("error registrando migración %s: %w", label, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	m.logger.Info("migración aplicada",
+		slog.String("migration", label),
+		slog.String("direction", direction),
+		slog.Duration("duration", time.Since(start)))
+
+	return nil
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
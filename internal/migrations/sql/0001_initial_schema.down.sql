@@ -0,0 +1,11 @@
+-- El rol notes_app y la extensión pg_stat_statements se conservan:
+-- son objetos del cluster que pueden usar otras bases.
+DROP FUNCTION IF EXISTS note_link_tenant(TEXT);
+DROP FUNCTION IF EXISTS purge_expired_idempotency_keys();
+DROP TABLE IF EXISTS slow_query_plans;
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_immutable();
+DROP TABLE IF EXISTS note_links;
+DROP TABLE IF EXISTS note_shares;
+DROP TABLE IF EXISTS notes;
@@ -0,0 +1,209 @@
+-- Esquema inicial. Sirve también para adoptar bases creadas con el antiguo init.sql:
+-- las tablas se crean con IF NOT EXISTS y las columnas que init.sql no tenía se
+-- agregan explícitamente más abajo.
+--
+-- Requiere el rol de la aplicación notes_app (scripts/create_app_role.sql): es un
+-- objeto del cluster y su contraseña es parte del despliegue, no del esquema.
+
+-- Tabla de notas
+-- tenant_id toma por defecto el tenant fijado en la transacción (app.tenant_id)
+CREATE TABLE IF NOT EXISTS notes (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.tenant_id'),
+    owner_id BIGINT,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Bases creadas con init.sql: notes no tenía tenant ni dueño. Las notas existentes
+-- pasan al tenant por defecto de la API (api.default_tenant) y quedan sin dueño.
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+UPDATE notes SET tenant_id = 'default' WHERE tenant_id IS NULL;
+ALTER TABLE notes ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id');
+ALTER TABLE notes ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS owner_id BIGINT;
+
+-- Índice para búsqueda por título (GIN para búsqueda de texto)
+CREATE INDEX IF NOT EXISTS idx_notes_title_gin 
+ON notes USING GIN (to_tsvector('simple', title));
+
+-- Índice compuesto para keyset pagination
+CREATE INDEX IF NOT EXISTS idx_notes_created_id 
+ON notes (created_at DESC, id DESC);
+
+-- init.sql creaba además idx_notes_created_at, redundante: es prefijo de idx_notes_created_id
+DROP INDEX IF EXISTS idx_notes_created_at;
+
+-- Índice compuesto para keyset pagination dentro de un tenant
+CREATE INDEX IF NOT EXISTS idx_notes_tenant_created_id 
+ON notes (tenant_id, created_at DESC, id DESC);
+
+-- Índice para notas por dueño
+CREATE INDEX IF NOT EXISTS idx_notes_owner_id
+ON notes (owner_id) WHERE owner_id IS NOT NULL;
+
+-- Notas compartidas con otros usuarios
+CREATE TABLE IF NOT EXISTS note_shares (
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.tenant_id'),
+    note_id BIGINT NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (note_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_shares_user
+ON note_shares (user_id, created_at DESC);
+
+-- Enlaces públicos de solo lectura (se guarda solo el hash del token)
+CREATE TABLE IF NOT EXISTS note_links (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.tenant_id'),
+    note_id BIGINT NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_links_note
+ON note_links (note_id);
+
+-- Log de auditoría append-only de las operaciones que modifican notas
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.tenant_id'),
+    actor_id BIGINT,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    note_id BIGINT NOT NULL,
+    before_hash TEXT,
+    after_hash TEXT,
+    request_id TEXT,
+    client_ip INET,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant_id
+ON audit_log (tenant_id, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_note
+ON audit_log (note_id, id DESC);
+
+-- Ni siquiera el dueño de la tabla puede modificar o borrar entradas
+CREATE OR REPLACE FUNCTION audit_log_immutable() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log es append-only';
+END
+$$;
+
+DROP TRIGGER IF EXISTS audit_log_immutable ON audit_log;
+CREATE TRIGGER audit_log_immutable
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_immutable();
+
+-- Claves de idempotencia (Idempotency-Key) con la respuesta guardada para reintentos
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    tenant_id TEXT NOT NULL DEFAULT current_setting('app.tenant_id'),
+    user_id BIGINT NOT NULL DEFAULT 0,
+    key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('in_progress', 'completed')),
+    response_status INT,
+    content_type TEXT,
+    response_body BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (tenant_id, user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires
+ON idempotency_keys (expires_at);
+
+-- Purga de claves vencidas de todos los tenants (SECURITY DEFINER, ignora RLS)
+CREATE OR REPLACE FUNCTION purge_expired_idempotency_keys() RETURNS BIGINT
+LANGUAGE plpgsql SECURITY DEFINER SET search_path = public AS $$
+DECLARE
+    deleted BIGINT;
+BEGIN
+    DELETE FROM idempotency_keys WHERE expires_at <= now();
+    GET DIAGNOSTICS deleted = ROW_COUNT;
+    RETURN deleted;
+END
+$$;
+
+-- Planes EXPLAIN (FORMAT JSON) de consultas lentas, capturados por la API.
+-- Es información de diagnóstico para administradores, sin RLS por tenant.
+CREATE TABLE IF NOT EXISTS slow_query_plans (
+    id BIGSERIAL PRIMARY KEY,
+    query TEXT NOT NULL,
+    duration_ms DOUBLE PRECISION NOT NULL,
+    plan JSONB NOT NULL,
+    request_id TEXT,
+    tenant_id TEXT,
+    captured_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Aislamiento multi-tenant con row-level security.
+-- La aplicación fija app.tenant_id por transacción (set_config(..., true));
+-- si no está fijado current_setting devuelve NULL y no se ve ninguna fila.
+-- FORCE aplica las políticas también al dueño de las tablas.
+ALTER TABLE notes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE notes FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON notes;
+CREATE POLICY tenant_isolation ON notes
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE note_shares ENABLE ROW LEVEL SECURITY;
+ALTER TABLE note_shares FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON note_shares;
+CREATE POLICY tenant_isolation ON note_shares
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE note_links ENABLE ROW LEVEL SECURITY;
+ALTER TABLE note_links FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON note_links;
+CREATE POLICY tenant_isolation ON note_links
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE audit_log ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_log FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_log;
+CREATE POLICY tenant_isolation ON audit_log
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE idempotency_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE idempotency_keys FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON idempotency_keys;
+CREATE POLICY tenant_isolation ON idempotency_keys
+    USING (tenant_id = current_setting('app.tenant_id', true))
+    WITH CHECK (tenant_id = current_setting('app.tenant_id', true));
+
+-- Los enlaces públicos son anónimos: esta función (SECURITY DEFINER, ignora RLS)
+-- solo revela el tenant al que pertenece un hash de token concreto
+CREATE OR REPLACE FUNCTION note_link_tenant(p_token_hash TEXT) RETURNS TEXT
+LANGUAGE sql STABLE SECURITY DEFINER SET search_path = public AS $$
+    SELECT tenant_id FROM note_links WHERE token_hash = p_token_hash
+$$;
+
+-- Permisos del rol de la aplicación: los superusuarios ignoran RLS, por eso la API
+-- debe conectarse con notes_app (ver DATABASE_URL)
+GRANT SELECT, INSERT, UPDATE, DELETE ON notes, note_shares, note_links TO notes_app;
+GRANT SELECT, INSERT ON audit_log TO notes_app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON idempotency_keys TO notes_app;
+GRANT SELECT, INSERT ON slow_query_plans TO notes_app;
+GRANT USAGE ON ALL SEQUENCES IN SCHEMA public TO notes_app;
+GRANT EXECUTE ON FUNCTION note_link_tenant(TEXT) TO notes_app;
+GRANT EXECUTE ON FUNCTION purge_expired_idempotency_keys() TO notes_app;
+
+-- Habilitar pg_stat_statements para monitoreo
+CREATE EXTENSION IF NOT EXISTS pg_stat_statements;
+
+-- Permitir que la API reinicie las estadísticas desde /api/v1/admin/statements/reset
+GRANT EXECUTE ON FUNCTION pg_stat_statements_reset TO notes_app;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS notes_notify_change ON notes;
+DROP FUNCTION IF EXISTS notify_note_change();
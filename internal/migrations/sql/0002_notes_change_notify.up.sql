@@ -0,0 +1,27 @@
+-- Notifica los cambios de notas por el canal note_changes para que cada instancia
+-- de la API invalide su cache local. El payload es JSON: {"tenant_id", "id", "op"}.
+CREATE OR REPLACE FUNCTION notify_note_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    note RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        note := OLD;
+    ELSE
+        note := NEW;
+    END IF;
+
+    PERFORM pg_notify('note_changes', json_build_object(
+        'tenant_id', note.tenant_id,
+        'id', note.id,
+        'op', lower(TG_OP)
+    )::text);
+
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS notes_notify_change ON notes;
+CREATE TRIGGER notes_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON notes
+FOR EACH ROW EXECUTE FUNCTION notify_note_change();
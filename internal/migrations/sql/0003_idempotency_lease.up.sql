@@ -0,0 +1,5 @@
+-- Las reservas en curso vencen a los locked_until: si el proceso que la tomó
+-- murió sin liberarla, otra petición con la misma clave puede tomarla.
+-- Las filas existentes quedan con la reserva ya vencida.
+ALTER TABLE idempotency_keys
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Tipos de hallazgo del asesor de índices
+const (
+	FindingUnusedIndex    = "unused_index"
+	FindingDuplicateIndex = "duplicate_index"
+	FindingRedundantIndex = "redundant_index"
+	FindingSeqScan        = "seq_scan"
+)
+
+// AdvisorReport es el resultado del asesor de índices.
+// Las estadísticas son acumuladas desde StatsReset: un índice "sin uso" puede
+// no haberse usado solo porque las estadísticas se reiniciaron hace poco.
+type AdvisorReport struct {
+	GeneratedAt         time.Time        `json:"generated_at"`
+	StatsReset          *time.Time       `json:"stats_reset,omitempty"`
+	StatementsAvailable bool             `json:"statements_available"`
+	Findings            []AdvisorFinding `json:"findings"`
+}
+
+// AdvisorFinding es un problema detectado con su DDL sugerido. Los hallazgos de
+// seq scan no traen DDL: en su lugar adjuntan las sentencias que leen la tabla.
+type AdvisorFinding struct {
+	Kind       string   `json:"kind"`
+	Table      string   `json:"table"`
+	Index      string   `json:"index,omitempty"`
+	Detail     string   `json:"detail"`
+	SizeBytes  int64    `json:"size_bytes"`
+	DDL        string   `json:"ddl,omitempty"`
+	Statements []string `json:"statements,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Acciones registradas en el log de auditoría
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	ActorID    *int64    `json:"actor_id,omitempty"`
+	Action     string    `json:"action"`
+	NoteID     int64     `json:"note_id"`
+	BeforeHash *string   `json:"before_hash,omitempty"`
+	AfterHash  *string   `json:"after_hash,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AuditParams struct {
+	Limit    int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	CursorID int64  `form:"cursor_id" binding:"omitempty,min=0"`
+	NoteID   int64  `form:"note_id" binding:"omitempty,min=1"`
+	ActorID  int64  `form:"actor_id" binding:"omitempty,min=1"`
+	Action   string `form:"action" binding:"omitempty,oneof=create update delete"`
+}
+
+type AuditPage struct {
+	Entries  []AuditEntry `json:"entries"`
+	NextPage bool         `json:"next_page"`
+	Cursor   string       `json:"cursor,omitempty"`
+}
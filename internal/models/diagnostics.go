@@ -0,0 +1,141 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SlowQuery es una sentencia que superó el umbral de consulta lenta, con su plan
+type SlowQuery struct {
+	ID         int64           `json:"id"`
+	Query      string          `json:"query"`
+	DurationMs float64         `json:"duration_ms"`
+	RequestID  string          `json:"request_id,omitempty"`
+	TenantID   string          `json:"tenant_id,omitempty"`
+	CapturedAt time.Time       `json:"captured_at"`
+	Plan       json.RawMessage `json:"plan,omitempty"`
+}
+
+// IndexUsage describe el uso y el tamaño de un índice.
+// EstimatedBloatBytes es una estimación para índices btree (nil si no aplica).
+type IndexUsage struct {
+	Schema              string `json:"schema"`
+	Table               string `json:"table"`
+	Index               string `json:"index"`
+	Definition          string `json:"definition"`
+	IsUnique            bool   `json:"is_unique"`
+	IsPrimary           bool   `json:"is_primary"`
+	Scans               int64  `json:"scans"`
+	TuplesRead          int64  `json:"tuples_read"`
+	TuplesFetched       int64  `json:"tuples_fetched"`
+	SizeBytes           int64  `json:"size_bytes"`
+	EstimatedBloatBytes *int64 `json:"estimated_bloat_bytes,omitempty"`
+}
+
+// TableSize describe el tamaño y la actividad de una tabla
+type TableSize struct {
+	Schema          string     `json:"schema"`
+	Table           string     `json:"table"`
+	TotalBytes      int64      `json:"total_bytes"`
+	TableBytes      int64      `json:"table_bytes"`
+	IndexBytes      int64      `json:"index_bytes"`
+	ToastBytes      int64      `json:"toast_bytes"`
+	LiveTuples      int64      `json:"live_tuples"`
+	DeadTuples      int64      `json:"dead_tuples"`
+	SeqScans        int64      `json:"seq_scans"`
+	SeqTuplesRead   int64      `json:"seq_tuples_read"`
+	IndexScans      int64      `json:"index_scans"`
+	LastVacuum      *time.Time `json:"last_vacuum,omitempty"`
+	LastAutovacuum  *time.Time `json:"last_autovacuum,omitempty"`
+	LastAnalyze     *time.Time `json:"last_analyze,omitempty"`
+	LastAutoanalyze *time.Time `json:"last_autoanalyze,omitempty"`
+}
+
+// StatementStats son las estadísticas de pg_stat_statements de una sentencia
+type StatementStats struct {
+	QueryID        int64   `json:"query_id"`
+	Query          string  `json:"query"`
+	Calls          int64   `json:"calls"`
+	TotalTimeMs    float64 `json:"total_time_ms"`
+	MeanTimeMs     float64 `json:"mean_time_ms"`
+	MaxTimeMs      float64 `json:"max_time_ms"`
+	Rows           int64   `json:"rows"`
+	SharedBlksHit  int64   `json:"shared_blks_hit"`
+	SharedBlksRead int64   `json:"shared_blks_read"`
+}
+
+// Session es una conexión activa de pg_stat_activity
+type Session struct {
+	PID             int32      `json:"pid"`
+	User            string     `json:"user"`
+	ApplicationName string     `json:"application_name"`
+	ClientAddr      string     `json:"client_addr,omitempty"`
+	State           string     `json:"state"`
+	WaitEventType   string     `json:"wait_event_type,omitempty"`
+	WaitEvent       string     `json:"wait_event,omitempty"`
+	BackendStart    time.Time  `json:"backend_start"`
+	QueryStart      *time.Time `json:"query_start,omitempty"`
+	QueryDurationMs *float64   `json:"query_duration_ms,omitempty"`
+	BlockedBy       []int32    `json:"blocked_by"`
+	Query           string     `json:"query"`
+}
+
+// CacheHitRatio es la proporción de lecturas servidas desde shared_buffers
+type CacheHitRatio struct {
+	Database float64           `json:"database"`
+	Tables   []TableCacheRatio `json:"tables"`
+}
+
+type TableCacheRatio struct {
+	Table      string   `json:"table"`
+	HeapRatio  *float64 `json:"heap_ratio,omitempty"`
+	IndexRatio *float64 `json:"index_ratio,omitempty"`
+}
+
+// Consultas del repositorio que se pueden analizar con EXPLAIN ANALYZE
+const (
+	ExplainListNotes     = "list_notes"
+	ExplainListNotesNext = "list_notes_next"
+	ExplainSearchNotes   = "search_notes"
+	ExplainNotesBatch    = "get_notes_batch"
+)
+
+// ExplainRequest indica la consulta del repositorio a analizar y sus parámetros.
+// UserID permite analizar la consulta tal como la vería ese usuario (0 = anónimo).
+type ExplainRequest struct {
+	Query      string    `json:"query" binding:"required"`
+	UserID     int64     `json:"user_id"`
+	Limit      int       `json:"limit" binding:"omitempty,min=1,max=100"`
+	CursorTime time.Time `json:"cursor_time"`
+	CursorID   int64     `json:"cursor_id"`
+	Search     string    `json:"search"`
+	IDs        []int64   `json:"ids" binding:"omitempty,max=100"`
+	Raw        bool      `json:"raw"`
+}
+
+// ExplainResult es el resumen de un EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON).
+// Plan contiene el plan completo solo si se pidió con raw.
+type ExplainResult struct {
+	Query           string          `json:"query"`
+	SQL             string          `json:"sql"`
+	PlanningTimeMs  float64         `json:"planning_time_ms"`
+	ExecutionTimeMs float64         `json:"execution_time_ms"`
+	IndexesUsed     []string        `json:"indexes_used"`
+	SeqScans        []string        `json:"seq_scans"`
+	Nodes           []PlanNode      `json:"nodes"`
+	Plan            json.RawMessage `json:"plan,omitempty"`
+}
+
+// PlanNode es un nodo del plan, aplanado en preorden; Depth indica su nivel en el árbol
+type PlanNode struct {
+	Depth             int     `json:"depth"`
+	NodeType          string  `json:"node_type"`
+	Relation          string  `json:"relation,omitempty"`
+	Index             string  `json:"index,omitempty"`
+	PlanRows          float64 `json:"plan_rows"`
+	ActualRows        float64 `json:"actual_rows"`
+	Loops             float64 `json:"loops"`
+	ActualTotalTimeMs float64 `json:"actual_total_time_ms"`
+	SharedHitBlocks   int64   `json:"shared_hit_blocks"`
+	SharedReadBlocks  int64   `json:"shared_read_blocks"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// Estados de una clave de idempotencia
+const (
+	IdempotencyInProgress = "in_progress"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyRecord es la respuesta guardada para un Idempotency-Key
+type IdempotencyRecord struct {
+	Key            string
+	RequestHash    string
+	Status         string
+	ResponseStatus int
+	ContentType    string
+	ResponseBody   []byte
+}
@@ -6,6 +6,7 @@ import (
 
 type Note struct {
 	ID        int64     `json:"id"`
+	OwnerID   *int64    `json:"owner_id,omitempty"`
 	Title     string    `json:"title"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
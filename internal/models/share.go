@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Roles con los que se puede compartir una nota
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+)
+
+type NoteShare struct {
+	NoteID    int64     `json:"note_id"`
+	UserID    int64     `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SharedNote es una nota compartida con el usuario junto con su rol
+type SharedNote struct {
+	Note
+	Role string `json:"role"`
+}
+
+type ShareNoteRequest struct {
+	UserID int64  `json:"user_id" binding:"required,min=1"`
+	Role   string `json:"role" binding:"required,oneof=viewer editor"`
+}
+
+type PublicLink struct {
+	ID        int64      `json:"id"`
+	NoteID    int64      `json:"note_id"`
+	Token     string     `json:"token,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreatePublicLinkRequest struct {
+	ExpiresInHours int `json:"expires_in_hours" binding:"omitempty,min=1,max=8760"`
+}
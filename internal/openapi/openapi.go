@@ -0,0 +1,190 @@
+// Package openapi genera un documento OpenAPI 3.1 a partir de las rutas de gin
+// y de los tipos de internal/models: los esquemas salen por reflexión de los tags
+// json, form y binding, así la especificación no se mantiene a mano.
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version es la versión de OpenAPI del documento generado
+const Version = "3.1.0"
+
+// Tipos de respuesta que no son JSON
+type (
+	// Text es una respuesta text/plain
+	Text struct{}
+	// HTML es una respuesta text/html
+	HTML struct{}
+)
+
+// ErrorBody es el cuerpo de error común de la API ({"error": "..."})
+type ErrorBody struct {
+	Error string `json:"error"`
+}
+
+// Spec describe las operaciones de la API; Build la combina con las rutas del router
+type Spec struct {
+	Info Info
+	// Parameters son parámetros reutilizables (headers comunes), referenciados por nombre
+	Parameters      map[string]Parameter
+	SecuritySchemes map[string]SecurityScheme
+	// Operations por "MÉTODO /ruta/de/gin", p. ej. "GET /api/v1/notes/:id"
+	Operations map[string]Operation
+}
+
+// Operation describe una ruta del router
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	// Parameters son nombres de Spec.Parameters
+	Parameters []string
+	// Query es un struct con tags form (y binding) que el handler enlaza con ShouldBindQuery
+	Query any
+	// QueryParams son parámetros que el handler lee uno a uno
+	QueryParams []Parameter
+	// Body es el cuerpo JSON de la petición; BodyOptional si el handler acepta no recibirlo
+	Body         any
+	BodyOptional bool
+	// Responses por código de estado; el valor es un ejemplo del tipo del cuerpo
+	// (nil sin cuerpo, Text, HTML o cualquier tipo serializable a JSON)
+	Responses map[int]any
+	// Security son nombres de Spec.SecuritySchemes
+	Security []string
+}
+
+// Build genera el documento con las rutas del router. Retorna además un error si
+// hay rutas sin operación descrita u operaciones sin ruta: las rutas sin describir
+// se incluyen igual, con solo su método y parámetros de path.
+func Build(spec Spec, routes gin.RoutesInfo) (*Document, error) {
+	schemas := newSchemaRegistry()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    spec.Info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:         schemas.schemas,
+			Parameters:      make(map[string]*Parameter),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+	}
+	for name, p := range spec.Parameters {
+		doc.Components.Parameters[name] = &p
+	}
+	for name, s := range spec.SecuritySchemes {
+		doc.Components.SecuritySchemes[name] = &s
+	}
+
+	var errs []error
+	seen := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		seen[key] = true
+
+		op, ok := spec.Operations[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("ruta sin describir en la especificación: %s", key))
+		}
+
+		path, pathParams := convertPath(route.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = buildOperation(schemas, op, pathParams)
+	}
+
+	for key := range spec.Operations {
+		if !seen[key] {
+			errs = append(errs, fmt.Errorf("operación sin ruta en el router: %s", key))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+
+	return doc, errors.Join(errs...)
+}
+
+func buildOperation(schemas *schemaRegistry, op Operation, pathParams []*Parameter) *OperationObject {
+	out := &OperationObject{
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Parameters:  pathParams,
+		Responses:   make(map[string]*ResponseObject),
+	}
+
+	for _, name := range op.Parameters {
+		out.Parameters = append(out.Parameters, &Parameter{Ref: "#/components/parameters/" + name})
+	}
+	if op.Query != nil {
+		out.Parameters = append(out.Parameters, schemas.queryParameters(reflect.TypeOf(op.Query))...)
+	}
+	for _, p := range op.QueryParams {
+		p.In = "query"
+		out.Parameters = append(out.Parameters, &p)
+	}
+
+	if op.Body != nil {
+		out.RequestBody = &RequestBody{
+			Required: !op.BodyOptional,
+			Content:  map[string]*MediaType{"application/json": {Schema: schemas.schemaFor(reflect.TypeOf(op.Body))}},
+		}
+	}
+
+	for _, name := range op.Security {
+		out.Security = append(out.Security, map[string][]string{name: {}})
+	}
+
+	for status, body := range op.Responses {
+		resp := &ResponseObject{Description: http.StatusText(status)}
+		switch body.(type) {
+		case nil:
+		case Text:
+			resp.Content = map[string]*MediaType{"text/plain": {Schema: &Schema{Type: "string"}}}
+		case HTML:
+			resp.Content = map[string]*MediaType{"text/html": {Schema: &Schema{Type: "string"}}}
+		default:
+			resp.Content = map[string]*MediaType{"application/json": {Schema: schemas.schemaFor(reflect.TypeOf(body))}}
+		}
+		out.Responses[strconv.Itoa(status)] = resp
+	}
+	if len(out.Responses) == 0 {
+		out.Responses["default"] = &ResponseObject{Description: "Respuesta"}
+	}
+	return out
+}
+
+// convertPath pasa la ruta de gin (/notes/:id) a OpenAPI (/notes/{id}) con sus
+// parámetros. Los parámetros id o *_id son enteros positivos; el resto, strings.
+func convertPath(path string) (string, []*Parameter) {
+	var params []*Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" || segment[0] != ':' && segment[0] != '*' {
+			continue
+		}
+		name := segment[1:]
+		segments[i] = "{" + name + "}"
+
+		schema := &Schema{Type: "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			schema = &Schema{Type: "integer", Format: "int64", Minimum: ptr(1.0)}
+		}
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,258 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaRegistry genera los esquemas de los tipos y guarda los structs con nombre
+// en components/schemas
+type schemaRegistry struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: make(map[string]*Schema)}
+}
+
+// schemaFor retorna el esquema del tipo: un $ref para los structs con nombre y un
+// esquema nuevo en los demás casos (se le pueden agregar restricciones)
+func (r *schemaRegistry) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &Schema{Type: "object"}
+		}
+		return &Schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		if _, ok := r.schemas[t.Name()]; !ok {
+			// Se registra antes de recorrer los campos por si el tipo es recursivo
+			r.schemas[t.Name()] = &Schema{}
+			*r.schemas[t.Name()] = *r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+
+	// interface{} y el resto: cualquier valor
+	return &Schema{}
+}
+
+// structSchema arma el objeto con los campos serializados por encoding/json.
+// Los structs con tags binding son de entrada: son obligatorios los campos con
+// binding:"required". En los de salida, los campos sin omitempty siempre están.
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	r.addFields(s, t, hasBindingTags(t))
+	return s
+}
+
+func (r *schemaRegistry) addFields(s *Schema, t reflect.Type, input bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// Los structs embebidos sin nombre en json se aplanan, como en encoding/json
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.addFields(s, embedded, input)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := strings.Contains(opts, "omitempty")
+
+		field := r.schemaFor(f.Type)
+		applyBinding(field, f.Type, f.Tag.Get("binding"))
+		if f.Type.Kind() == reflect.Pointer && !omitempty {
+			field = nullable(field)
+		}
+		s.Properties[name] = field
+
+		required := !omitempty
+		if input {
+			required = hasRule(f.Tag.Get("binding"), "required")
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// queryParameters convierte los campos con tag form en parámetros de query
+func (r *schemaRegistry) queryParameters(t reflect.Type) []*Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		schema := r.schemaFor(f.Type)
+		applyBinding(schema, f.Type, f.Tag.Get("binding"))
+		params = append(params, &Parameter{
+			Name:     name,
+			In:       "query",
+			Required: hasRule(f.Tag.Get("binding"), "required"),
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+// applyBinding traduce las reglas de validación de gin (min, max, gte, lte, len,
+// oneof) a restricciones del esquema según el tipo del campo
+func applyBinding(s *Schema, t reflect.Type, binding string) {
+	if binding == "" || s.Ref != "" {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for _, rule := range strings.Split(binding, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			// Las reglas siguientes aplican a los elementos
+			return
+		}
+
+		switch name {
+		case "min", "gte":
+			setBound(s, t, arg, true)
+		case "max", "lte":
+			setBound(s, t, arg, false)
+		case "len":
+			setBound(s, t, arg, true)
+			setBound(s, t, arg, false)
+		case "oneof":
+			for _, v := range strings.Fields(arg) {
+				s.Enum = append(s.Enum, enumValue(t, v))
+			}
+		}
+	}
+}
+
+func setBound(s *Schema, t reflect.Type, arg string, lower bool) {
+	n, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if lower {
+			s.MinLength = ptr(int(n))
+		} else {
+			s.MaxLength = ptr(int(n))
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if lower {
+			s.MinItems = ptr(int(n))
+		} else {
+			s.MaxItems = ptr(int(n))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if lower {
+			s.Minimum = ptr(n)
+		} else {
+			s.Maximum = ptr(n)
+		}
+	}
+}
+
+func enumValue(t reflect.Type, v string) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+// nullable admite null además del tipo del esquema (JSON Schema 2020-12)
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{OneOf: []*Schema{s, {Type: "null"}}}
+	}
+	if typ, ok := s.Type.(string); ok {
+		s.Type = []string{typ, "null"}
+	}
+	return s
+}
+
+func hasBindingTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("binding"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRule(binding, rule string) bool {
+	for _, r := range strings.Split(binding, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package pb
+
+import (
+	"github.com/ybotet/notes-api-optimization/internal/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Conversiones entre los mensajes generados y internal/models
+
+func NoteFromModel(n *models.Note) *Note {
+	if n == nil {
+		return nil
+	}
+	return &Note{
+		Id:        n.ID,
+		OwnerId:   n.OwnerID,
+		Title:     n.Title,
+		Content:   n.Content,
+		CreatedAt: timestamppb.New(n.CreatedAt),
+		UpdatedAt: timestamppb.New(n.UpdatedAt),
+	}
+}
+
+func NotesFromModels(notes []models.Note) []*Note {
+	out := make([]*Note, len(notes))
+	for i := range notes {
+		out[i] = NoteFromModel(&notes[i])
+	}
+	return out
+}
+
+func NoteListFromModels(notes []models.Note) *NoteList {
+	return &NoteList{Notes: NotesFromModels(notes)}
+}
+
+func NotesPageFromModel(page *models.NotesPage) *NotesPage {
+	if page == nil {
+		return nil
+	}
+	out := &NotesPage{
+		Notes:    NotesFromModels(page.Notes),
+		NextPage: page.NextPage,
+		Cursor:   page.Cursor,
+		Total:    page.Total,
+	}
+	if page.NextPage && len(page.Notes) > 0 {
+		last := page.Notes[len(page.Notes)-1]
+		out.NextCursorTime = timestamppb.New(last.CreatedAt)
+		out.NextCursorId = last.ID
+	}
+	return out
+}
+
+func (r *CreateNoteRequest) ToModel() models.CreateNoteRequest {
+	return models.CreateNoteRequest{Title: r.GetTitle(), Content: r.GetContent()}
+}
+
+func (r *UpdateNoteRequest) ToModel() models.UpdateNoteRequest {
+	return models.UpdateNoteRequest{Title: r.GetTitle(), Content: r.GetContent()}
+}
+
+func NoteShareFromModel(s *models.NoteShare) *NoteShare {
+	if s == nil {
+		return nil
+	}
+	return &NoteShare{
+		NoteId:    s.NoteID,
+		UserId:    s.UserID,
+		Role:      s.Role,
+		CreatedAt: timestamppb.New(s.CreatedAt),
+	}
+}
+
+func NoteShareListFromModels(shares []models.NoteShare) *NoteShareList {
+	out := &NoteShareList{Shares: make([]*NoteShare, len(shares))}
+	for i := range shares {
+		out.Shares[i] = NoteShareFromModel(&shares[i])
+	}
+	return out
+}
+
+func SharedNoteListFromModels(notes []models.SharedNote) *SharedNoteList {
+	out := &SharedNoteList{Notes: make([]*SharedNote, len(notes))}
+	for i := range notes {
+		out.Notes[i] = &SharedNote{Note: NoteFromModel(&notes[i].Note), Role: notes[i].Role}
+	}
+	return out
+}
+
+func PublicLinkFromModel(l *models.PublicLink) *PublicLink {
+	if l == nil {
+		return nil
+	}
+	out := &PublicLink{
+		Id:        l.ID,
+		NoteId:    l.NoteID,
+		Token:     l.Token,
+		Url:       l.URL,
+		CreatedAt: timestamppb.New(l.CreatedAt),
+	}
+	if l.ExpiresAt != nil {
+		out.ExpiresAt = timestamppb.New(*l.ExpiresAt)
+	}
+	if l.RevokedAt != nil {
+		out.RevokedAt = timestamppb.New(*l.RevokedAt)
+	}
+	return out
+}
+
+func PublicLinkListFromModels(links []models.PublicLink) *PublicLinkList {
+	out := &PublicLinkList{Links: make([]*PublicLink, len(links))}
+	for i := range links {
+		out.Links[i] = PublicLinkFromModel(&links[i])
+	}
+	return out
+}
+
+func (r *ShareNoteRequest) ToModel() models.ShareNoteRequest {
+	return models.ShareNoteRequest{UserID: r.GetUserId(), Role: r.GetRole()}
+}
+
+func (r *CreatePublicLinkRequest) ToModel() models.CreatePublicLinkRequest {
+	return models.CreatePublicLinkRequest{ExpiresInHours: int(r.GetExpiresInHours())}
+}
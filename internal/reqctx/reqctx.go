@@ -0,0 +1,56 @@
+package reqctx
+
+import "context"
+
+type contextKey int
+
+const (
+	userIDKey contextKey = iota
+	tenantIDKey
+	requestIDKey
+	clientIPKey
+)
+
+// WithUserID guarda el ID del usuario autenticado en el contexto
+func WithUserID(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserID retorna el ID del usuario del contexto (0 si es anónimo)
+func UserID(ctx context.Context) int64 {
+	id, _ := ctx.Value(userIDKey).(int64)
+	return id
+}
+
+// WithTenantID guarda el tenant (workspace) de la petición en el contexto
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+// TenantID retorna el tenant del contexto ("" si no se definió)
+func TenantID(ctx context.Context) string {
+	id, _ := ctx.Value(tenantIDKey).(string)
+	return id
+}
+
+// WithRequestID guarda el ID de la petición en el contexto
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID retorna el ID de la petición del contexto ("" si no hay)
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithClientIP guarda la IP del cliente en el contexto
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIP retorna la IP del cliente del contexto ("" si no hay)
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
@@ -0,0 +1,12 @@
+-- Rol sin privilegios con el que se conecta la API (los superusuarios ignoran RLS).
+-- Aplicar como superusuario antes de las migraciones, que le otorgan los permisos:
+--   psql -v app_password=... -f scripts/create_app_role.sql
+-- (make db-role usa APP_DB_PASSWORD)
+\set ON_ERROR_STOP on
+
+SELECT format('CREATE ROLE notes_app LOGIN PASSWORD %L NOSUPERUSER NOBYPASSRLS', :'app_password')
+WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'notes_app')
+\gexec
+
+-- Vistas de monitoreo (pg_stat_activity, pg_stat_statements) para /api/v1/admin
+GRANT pg_monitor TO notes_app;
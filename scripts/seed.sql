@@ -0,0 +1,10 @@
+-- Datos de prueba en el tenant por defecto (aplicar después de las migraciones):
+--   make db-seed
+SELECT set_config('app.tenant_id', 'default', false);
+
+INSERT INTO notes (title, content) 
+SELECT 
+    'Примечание ' || i,
+    'Содержание примечания номер ' || i
+FROM generate_series(1, 1000) AS i
+ON CONFLICT DO NOTHING;